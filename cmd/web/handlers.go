@@ -1,11 +1,20 @@
 package main
 
 import (
+	"cmp"
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/iam-vl/snbox/internal/diff"
+	"github.com/iam-vl/snbox/internal/highlight"
 	"github.com/iam-vl/snbox/internal/models"
 	"github.com/iam-vl/snbox/internal/validator"
 	"github.com/julienschmidt/httprouter"
@@ -18,23 +27,1090 @@ import (
 // Ex: we tell the decoder to store the val with name "title" in Title
 // "-" - Ignore field during decoding
 type SnippetCreateForm struct {
+	Title               string            `form:"title"`
+	Files               []SnippetFileForm `form:"files"`    // files[0].name, files[0].content, ...
+	AddFile             bool              `form:"add_file"` // Set by the "Add another file" button
+	Expires             string            `form:"expires"`  // Key of an expiry policy option
+	Tags                string            `form:"tags"`     // Comma or space separated
+	Visibility          string            `form:"visibility"`
+	Passphrase          string            `form:"passphrase"` // Optional
+	MaxViews            int               `form:"max_views"`  // 0 for no limit, 1 burns after reading
+	validator.Validator `form:"-"`
+	// FieldErrors map[string]string
+}
+
+// SnippetFileForm is one file of the create form.
+type SnippetFileForm struct {
+	Name     string `form:"name"` // Defaults to snippet.txt, file2.txt, ...
+	Content  string `form:"content"`
+	Language string `form:"language"` // Detected when empty
+}
+
+// defaultFileName names the i-th file when the author left the name blank.
+func defaultFileName(i int) string {
+	if i == 0 {
+		return "snippet.txt"
+	}
+	return fmt.Sprintf("file%d.txt", i+1)
+}
+
+// compactFiles drops the rows after the first one that were left blank,
+// so unused "add file" rows do not become empty files.
+func compactFiles(files []SnippetFileForm) []SnippetFileForm {
+	compact := []SnippetFileForm{}
+	for i, f := range files {
+		if i == 0 || strings.TrimSpace(f.Name) != "" || strings.TrimSpace(f.Content) != "" {
+			compact = append(compact, f)
+		}
+	}
+	if len(compact) == 0 {
+		compact = append(compact, SnippetFileForm{})
+	}
+	return compact
+}
+
+func (app *application) HandleViewSnippet(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := strconv.Atoi(params.ByName("id"))
+	// id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id < 1 {
+		// http.NotFound(w, r)
+		app.NotFound(w)
+		return
+	}
+	// Use SnippetModel's Get
+	snippet, ok := app.visibleSnippet(w, r, id)
+	if !ok {
+		return
+	}
+	app.renderSnippet(w, r, snippet, "")
+}
+
+// /s/:token opens an unlisted snippet through its share link
+func (app *application) HandleSharedSnippet(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	snippet, err := app.snippets.GetShared(params.ByName("token"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.renderSnippet(w, r, snippet, params.ByName("token"))
+}
+
+// renderSnippet shows the latest revision of a snippet the caller may see,
+// or the unlock form if it is locked, or the reveal interstitial if reading it
+// uses up one of its limited views. shareToken is set when the caller came
+// through the share link, so that the forms lead back there.
+func (app *application) renderSnippet(w http.ResponseWriter, r *http.Request, snippet *models.Snippet, shareToken string) {
+	if !app.IsUnlocked(r, snippet) {
+		app.renderUnlock(w, http.StatusOK, r, snippet, SnippetUnlockForm{Token: shareToken})
+		return
+	}
+	if app.CountsViews(r, snippet) {
+		app.renderReveal(w, r, snippet, shareToken)
+		return
+	}
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	app.countView(r, snippet)
+	data, err := app.snippetData(r, snippet, revisions, revisions[len(revisions)-1])
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	data.ShareToken = shareToken
+	app.Render(w, http.StatusOK, "view.tmpl", data)
+}
+
+// countView records a read of snippet by somebody other than its owner, and
+// adds the reads not flushed to the database yet to snippet.Views.
+func (app *application) countView(r *http.Request, snippet *models.Snippet) {
+	if !app.IsSnippetOwner(r, snippet) {
+		app.views.Add(snippet.ID)
+	}
+	snippet.Views += app.views.Pending(snippet.ID)
+}
+
+// snippetData fills in what view.tmpl needs to show revision of snippet
+// and its discussion. Review comments on that revision go beside their lines,
+// the rest of the discussion below the snippet.
+func (app *application) snippetData(r *http.Request, snippet *models.Snippet, revisions []*models.Revision, revision *models.Revision) (*templateData, error) {
+	comments, err := app.comments.Thread(snippet.ID)
+	if err != nil {
+		return nil, err
+	}
+	//  Retrieve the flash value from the context
+	// flash := app.sessionManager.PopString(r.Context(), "flash")
+	data := app.NewTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+	data.Revision = revision
+	data.IsOwner = app.IsSnippetOwner(r, snippet)
+	if data.IsOwner {
+		data.Languages = highlight.Languages // For the language override
+	}
+	data.Comments, data.Review = review(snippet, revision, comments)
+	if data.UserID != 0 {
+		data.Starred, err = app.stars.Starred(data.UserID, snippet.ID)
+		if err != nil {
+			return nil, err
+		}
+		// For the "add to collection" form: only snippets others can open by id
+		if snippet.Visibility == models.Public || data.IsOwner {
+			data.Collections, err = app.collections.Owned(data.UserID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	data.Form = CommentForm{Revision: revision.Number}
+	// Pass flash to the template
+	// data.Flash = flash
+	return data, nil
+}
+
+// review takes the threads started by review comments on revision out of
+// comments and cuts the main file after their last lines, so that each one
+// shows beside the code it is about. Comments on other revisions stay in
+// the general discussion. If highlighting fails the discussion is left whole.
+func review(snippet *models.Snippet, revision *models.Revision, comments []*models.Comment) ([]*models.Comment, []ReviewPiece) {
+	if len(snippet.Files) == 0 {
+		return comments, nil
+	}
+	rest := []*models.Comment{}
+	threads := map[string]*ReviewThread{}
+	var current *ReviewThread // Thread the replies being walked belong to
+	for _, c := range comments {
+		if c.Depth == 0 {
+			current = nil
+			if c.IsReview() && c.Revision == revision.Number {
+				current = threads[c.Anchor()]
+				if current == nil {
+					current = &ReviewThread{Anchor: c.Anchor(), LineStart: c.LineStart, LineEnd: c.LineEnd}
+					threads[c.Anchor()] = current
+				}
+			}
+		}
+		if current != nil {
+			current.Comments = append(current.Comments, c)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	if len(threads) == 0 {
+		return comments, nil
+	}
+
+	sorted := make([]*ReviewThread, 0, len(threads))
+	for _, t := range threads {
+		sorted = append(sorted, t)
+	}
+	slices.SortFunc(sorted, func(a, b *ReviewThread) int {
+		return cmp.Or(a.LineEnd-b.LineEnd, a.LineStart-b.LineStart)
+	})
+	var after []int
+	var marked [][2]int
+	for _, t := range sorted {
+		if len(after) == 0 || after[len(after)-1] != t.LineEnd {
+			after = append(after, t.LineEnd)
+		}
+		marked = append(marked, [2]int{t.LineStart, t.LineEnd})
+	}
+	file := snippet.Files[0]
+	pieces, err := highlight.HTMLSplit(file.Content, file.Language, "L", after, marked)
+	if err != nil {
+		return comments, nil
+	}
+	out := make([]ReviewPiece, len(pieces))
+	for i, code := range pieces {
+		out[i].Code = code
+		for _, t := range sorted {
+			if i < len(after) && t.LineEnd == after[i] {
+				out[i].Threads = append(out[i].Threads, *t)
+			}
+		}
+	}
+	return rest, out
+}
+
+// /snippet/view/:id/rev/:n shows an older revision with the same page as the latest one
+func (app *application) HandleViewRevision(w http.ResponseWriter, r *http.Request) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	n, ok := ParamInt(r, "n")
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	snippet, ok := app.visibleSnippet(w, r, id)
+	if !ok {
+		return
+	}
+	if !app.IsUnlocked(r, snippet) {
+		app.renderUnlock(w, http.StatusOK, r, snippet, SnippetUnlockForm{})
+		return
+	}
+	if app.CountsViews(r, snippet) {
+		app.renderReveal(w, r, snippet, "")
+		return
+	}
+	revisions, err := app.snippets.Revisions(id)
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	if n > len(revisions) {
+		app.NotFound(w)
+		return
+	}
+	revision := revisions[n-1]
+	// Show the old text inside the snippet's own frame (id, created, expires)
+	old := *snippet
+	old.Title = revision.Title
+	old.Content = revision.Content
+	// Only the main file has a history
+	old.Files = append([]models.File(nil), snippet.Files...)
+	if len(old.Files) > 0 {
+		old.Files[0].Content = revision.Content
+	}
+
+	data, err := app.snippetData(r, &old, revisions, revision)
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	app.Render(w, http.StatusOK, "view.tmpl", data)
+}
+
+type SnippetEditForm struct {
 	Title               string `form:"title"`
 	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
 	validator.Validator `form:"-"`
-	// FieldErrors map[string]string
 }
 
-func (app *application) HandleViewSnippet(w http.ResponseWriter, r *http.Request) {
-	params := httprouter.ParamsFromContext(r.Context())
-	id, err := strconv.Atoi(params.ByName("id"))
-	// id, err := strconv.Atoi(r.URL.Query().Get("id"))
-	if err != nil || id < 1 {
-		// http.NotFound(w, r)
+// snippet/edit/:id
+func (app *application) HandleSnippetEditForm(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	data := app.NewTemplateData(r)
+	data.Snippet = snippet
+	data.Form = SnippetEditForm{
+		Title:   snippet.Title,
+		Content: snippet.Content,
+	}
+	app.Render(w, http.StatusOK, "edit.tmpl", data)
+}
+
+func (app *application) HandleEditSnippet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	var form SnippetEditForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be longer than 100 chars")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	if !form.Valid8() {
+		data := app.NewTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.Render(w, http.StatusUnprocessableEntity, "edit.tmpl", data)
+		return
+	}
+
+	n, err := app.snippets.Update(snippet.ID, app.AuthenticatedUserID(r), form.Title, form.Content)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoChange):
+			app.sessionManager.Put(r.Context(), "flash", "No changes to save")
+			http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+		case errors.Is(err, models.ErrNoRecord):
+			app.NotFound(w)
+		default:
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Snippet saved as revision %d", n))
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+}
+
+// POST /snippet/delete/:id moves the snippet to the owner's trash
+func (app *application) HandleDeleteSnippet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Snippet moved to the trash")
+	http.Redirect(w, r, "/user/trash", http.StatusSeeOther)
+}
+
+type SnippetForkForm struct {
+	Token string `form:"token"` // Share token, when forking through /s/:token
+}
+
+// POST /snippet/fork/:id copies a snippet the caller can read into a new one
+// they own. The fork keeps the parent's files, tags, visibility and passphrase,
+// and gets the default lifetime for the caller's role.
+func (app *application) HandleForkSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	var form SnippetForkForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	parent, next, ok := app.postedSnippet(w, r, id, form.Token)
+	if !ok {
+		return
+	}
+	if !app.IsUnlocked(r, parent) {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	// A copy would hand out the content without using up a view
+	if app.CountsViews(r, parent) {
+		app.ClientError(w, http.StatusForbidden)
+		return
+	}
+	role, err := app.users.Role(app.AuthenticatedUserID(r))
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	forkID, err := app.snippets.Insert(models.NewSnippet{
+		Title:      parent.Title,
+		Files:      parent.Files,
+		Expires:    app.expiry.DefaultFor(role),
+		Role:       role,
+		OwnerID:    app.AuthenticatedUserID(r),
+		Tags:       parent.Tags,
+		Visibility: parent.Visibility,
+		ForkedFrom: parent.ID,
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Forked snippet #%d", parent.ID))
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", forkID), http.StatusSeeOther)
+}
+
+type SnippetStarForm struct {
+	Starred bool   `form:"starred"` // false takes the star back
+	Token   string `form:"token"`   // Share token, when starring through /s/:token
+}
+
+// POST /snippet/star/:id stars (or unstars) a snippet the caller can see
+func (app *application) HandleStarSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	var form SnippetStarForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	snippet, next, ok := app.postedSnippet(w, r, id, form.Token)
+	if !ok {
+		return
+	}
+	if !app.IsUnlocked(r, snippet) {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	userID := app.AuthenticatedUserID(r)
+	flash := "Star removed"
+	if form.Starred {
+		err = app.stars.Star(userID, snippet.ID)
+		flash = "Snippet starred"
+	} else {
+		err = app.stars.Unstar(userID, snippet.ID)
+	}
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+type CommentForm struct {
+	Body     string `form:"body"`
+	ParentID int    `form:"parent_id"` // Comment replied to, 0 for a new thread
+	Token    string `form:"token"`     // Share token, when commenting through /s/:token
+	// Lines of a review comment, in the revision the commenter was reading.
+	// LineEnd defaults to LineStart; neither is set for general comments.
+	Revision            int `form:"revision"`
+	LineStart           int `form:"line_start"`
+	LineEnd             int `form:"line_end"`
+	validator.Validator `form:"-"`
+}
+
+func (form *CommentForm) check() {
+	form.CheckField(validator.NotBlank(form.Body), "body", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Body, models.MaxCommentLength), "body", fmt.Sprintf("This field cannot be longer than %d chars", models.MaxCommentLength))
+}
+
+// checkLines validates the line range of a new top-level comment against
+// the revision it refers to. Replies are about their parent's lines.
+func (form *CommentForm) checkLines(revisions []*models.Revision) {
+	if form.ParentID != 0 || (form.LineStart == 0 && form.LineEnd == 0) {
+		form.LineStart, form.LineEnd = 0, 0
+		return
+	}
+	if form.LineEnd == 0 {
+		form.LineEnd = form.LineStart
+	}
+	if form.Revision < 1 || form.Revision > len(revisions) {
+		form.AddFieldError("lines", "This revision does not exist")
+		return
+	}
+	lines := highlight.Lines(revisions[form.Revision-1].Content)
+	form.CheckField(form.LineStart >= 1 && form.LineStart <= form.LineEnd && form.LineEnd <= lines, "lines",
+		fmt.Sprintf("Lines must run forward, from 1 to at most %d", lines))
+}
+
+// POST /snippet/comments/:id adds a comment, or a reply to one, under a snippet
+func (app *application) HandleCreateComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	var form CommentForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	snippet, next, ok := app.postedSnippet(w, r, id, form.Token)
+	if !ok {
+		return
+	}
+	// Only readers who can see the page may join the discussion
+	if !app.IsUnlocked(r, snippet) || app.CountsViews(r, snippet) {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	if snippet.CommentsLocked {
+		app.ClientError(w, http.StatusForbidden)
+		return
+	}
+	revisions, err := app.snippets.Revisions(id)
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	form.check()
+	form.checkLines(revisions)
+	if !form.Valid8() {
+		data, err := app.snippetData(r, snippet, revisions, revisions[len(revisions)-1])
+		if err != nil {
+			app.ServerError(w, err)
+			return
+		}
+		data.ShareToken = form.Token
+		data.Form = form
+		app.Render(w, http.StatusUnprocessableEntity, "view.tmpl", data)
+		return
+	}
+	n := models.NewComment{
+		SnippetID: id,
+		ParentID:  form.ParentID,
+		AuthorID:  app.AuthenticatedUserID(r),
+		Body:      form.Body,
+	}
+	if form.LineStart > 0 {
+		n.Revision, n.LineStart, n.LineEnd = form.Revision, form.LineStart, form.LineEnd
+	}
+	commentID, err := app.comments.Insert(n)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Comment posted")
+	http.Redirect(w, r, fmt.Sprintf("%s#comment-%d", next, commentID), http.StatusSeeOther)
+}
+
+// ownComment loads the :id comment for an action only its author may take,
+// and the snippet it belongs to, which the author must still be able to see.
+// It writes the error response itself and returns false if the caller should stop.
+func (app *application) ownComment(w http.ResponseWriter, r *http.Request, token string) (*models.Comment, *models.Snippet, string, bool) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return nil, nil, "", false
+	}
+	comment, err := app.comments.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return nil, nil, "", false
+	}
+	if comment.AuthorID == 0 || comment.AuthorID != app.AuthenticatedUserID(r) {
+		app.ClientError(w, http.StatusForbidden)
+		return nil, nil, "", false
+	}
+	snippet, next, ok := app.postedSnippet(w, r, comment.SnippetID, token)
+	if !ok {
+		return nil, nil, "", false
+	}
+	return comment, snippet, next, true
+}
+
+// comment/edit/:id?token=...
+func (app *application) HandleCommentEditForm(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	comment, snippet, _, ok := app.ownComment(w, r, token)
+	if !ok {
+		return
+	}
+	if snippet.CommentsLocked {
+		app.ClientError(w, http.StatusForbidden)
+		return
+	}
+	data := app.NewTemplateData(r)
+	data.Snippet = snippet
+	data.Comment = comment
+	data.Form = CommentForm{Body: comment.Body, Token: token}
+	app.Render(w, http.StatusOK, "comment.tmpl", data)
+}
+
+func (app *application) HandleEditComment(w http.ResponseWriter, r *http.Request) {
+	var form CommentForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	comment, snippet, next, ok := app.ownComment(w, r, form.Token)
+	if !ok {
+		return
+	}
+	if snippet.CommentsLocked {
+		app.ClientError(w, http.StatusForbidden)
+		return
+	}
+	form.check()
+	if !form.Valid8() {
+		data := app.NewTemplateData(r)
+		data.Snippet = snippet
+		data.Comment = comment
+		data.Form = form
+		app.Render(w, http.StatusUnprocessableEntity, "comment.tmpl", data)
+		return
+	}
+	err = app.comments.Update(comment.ID, form.Body)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Comment updated")
+	http.Redirect(w, r, fmt.Sprintf("%s#comment-%d", next, comment.ID), http.StatusSeeOther)
+}
+
+// POST /comment/delete/:id blanks one of the caller's comments, even in a locked thread
+func (app *application) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	var form CommentForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	comment, _, next, ok := app.ownComment(w, r, form.Token)
+	if !ok {
+		return
+	}
+	err = app.comments.Delete(comment.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Comment deleted")
+	http.Redirect(w, r, next+"#comments", http.StatusSeeOther)
+}
+
+type CommentLockForm struct {
+	Locked bool `form:"locked"`
+}
+
+// POST /snippet/comments/:id/lock lets the owner close or reopen the discussion
+func (app *application) HandleLockComments(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	var form CommentLockForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.snippets.LockComments(snippet.ID, form.Locked)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	flash := "Comments reopened"
+	if form.Locked {
+		flash = "Comments locked"
+	}
+	app.sessionManager.Put(r.Context(), "flash", flash)
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d#comments", snippet.ID), http.StatusSeeOther)
+}
+
+// POST /snippet/language/:id replaces the detected (or chosen) language with the owner's pick
+func (app *application) HandleSetLanguage(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+	var form SnippetLanguageForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil || (form.Language != "" && !validator.PermittedValue(form.Language, highlight.Names()...)) {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.snippets.SetLanguage(snippet.ID, form.File, form.Language)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Language of %s set to %s", form.File, highlight.Label(form.Language)))
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+}
+
+// /snippet/raw/:id/:name serves one file of a snippet as plain text
+func (app *application) HandleRawFile(w http.ResponseWriter, r *http.Request) {
+	app.serveFile(w, r, false)
+}
+
+// /snippet/download/:id/:name serves one file of a snippet as an attachment
+func (app *application) HandleDownloadFile(w http.ResponseWriter, r *http.Request) {
+	app.serveFile(w, r, true)
+}
+
+// serveFile checks access like the snippet page does. Visitors of an
+// unlisted snippet pass its share token as ?token=.
+func (app *application) serveFile(w http.ResponseWriter, r *http.Request, attachment bool) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	snippet, _, ok := app.postedSnippet(w, r, id, token)
+	if !ok {
+		return
+	}
+	if !app.IsUnlocked(r, snippet) {
+		app.renderUnlock(w, http.StatusOK, r, snippet, SnippetUnlockForm{Token: token})
+		return
+	}
+	if app.CountsViews(r, snippet) {
+		app.renderReveal(w, r, snippet, token)
+		return
+	}
+	file, ok := snippet.File(httprouter.ParamsFromContext(r.Context()).ByName("name"))
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if attachment {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	}
+	io.WriteString(w, file.Content)
+}
+
+// user/trash
+func (app *application) HandleTrash(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.Trash(app.AuthenticatedUserID(r))
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	data := app.NewTemplateData(r)
+	data.Snippets = snippets
+	data.TrashDays = int(app.trashRetention.Hours() / 24)
+	app.Render(w, http.StatusOK, "trash.tmpl", data)
+}
+
+type CollectionForm struct {
+	Name                string `form:"name"`
+	Visibility          string `form:"visibility"`
+	validator.Validator `form:"-"`
+}
+
+// user/collections lists the caller's collections, above the form to create one
+func (app *application) HandleUserCollections(w http.ResponseWriter, r *http.Request) {
+	app.renderCollections(w, r, http.StatusOK, CollectionForm{Visibility: string(models.Public)})
+}
+
+func (app *application) renderCollections(w http.ResponseWriter, r *http.Request, status int, form CollectionForm) {
+	collections, err := app.collections.Owned(app.AuthenticatedUserID(r))
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	data := app.NewTemplateData(r)
+	data.Collections = collections
+	data.Form = form
+	app.Render(w, status, "collections.tmpl", data)
+}
+
+// POST /user/collections creates an empty collection
+func (app *application) HandleCreateCollection(w http.ResponseWriter, r *http.Request) {
+	var form CollectionForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	form.Name = strings.TrimSpace(form.Name)
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, models.MaxCollectionNameLength), "name", fmt.Sprintf("This field cannot be longer than %d chars", models.MaxCollectionNameLength))
+	form.CheckField(validator.PermittedValue(models.Visibility(form.Visibility), models.Visibilities...), "visibility", "This field must be public, unlisted or private")
+	if !form.Valid8() {
+		app.renderCollections(w, r, http.StatusUnprocessableEntity, form)
+		return
+	}
+	id, err := app.collections.Insert(app.AuthenticatedUserID(r), form.Name, models.Visibility(form.Visibility))
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Collection created")
+	http.Redirect(w, r, fmt.Sprintf("/collection/%d", id), http.StatusSeeOther)
+}
+
+// /collection/:id shows a public collection, or one of the caller's own
+func (app *application) HandleViewCollection(w http.ResponseWriter, r *http.Request) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	collection, err := app.collections.Get(id, app.AuthenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	// Same rule as snippets: unlisted collections only open through their share link
+	if collection.Visibility != models.Public && !app.IsCollectionOwner(r, collection) {
+		app.NotFound(w)
+		return
+	}
+	app.renderCollection(w, r, collection)
+}
+
+// /c/:token opens an unlisted collection through its share link
+func (app *application) HandleSharedCollection(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	collection, err := app.collections.GetShared(params.ByName("token"), app.AuthenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.renderCollection(w, r, collection)
+}
+
+func (app *application) renderCollection(w http.ResponseWriter, r *http.Request, collection *models.Collection) {
+	data := app.NewTemplateData(r)
+	data.Collection = collection
+	data.IsOwner = app.IsCollectionOwner(r, collection)
+	app.Render(w, http.StatusOK, "collection.tmpl", data)
+}
+
+// ownedCollection loads the :id collection for an owner-only action.
+// It writes the 404 / 403 response itself and returns false if the caller should stop.
+func (app *application) ownedCollection(w http.ResponseWriter, r *http.Request) (*models.Collection, bool) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return nil, false
+	}
+	collection, err := app.collections.Get(id, app.AuthenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return nil, false
+	}
+	if !app.IsCollectionOwner(r, collection) {
+		app.ClientError(w, http.StatusForbidden)
+		return nil, false
+	}
+	return collection, true
+}
+
+type CollectionEntryForm struct {
+	SnippetID int `form:"snippet_id"`
+	Position  int `form:"position"` // New place of the entry, 1 for the top; only used by move
+}
+
+// POST /collection/:id/add appends a snippet the owner can see to their collection
+func (app *application) HandleAddToCollection(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+	var form CollectionEntryForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	snippet, ok := app.visibleSnippet(w, r, form.SnippetID)
+	if !ok {
+		return
+	}
+	err = app.collections.Add(collection.ID, snippet.ID)
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Added to %s", collection.Name))
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", snippet.ID), http.StatusSeeOther)
+}
+
+// POST /collection/:id/remove takes a snippet out of the owner's collection
+func (app *application) HandleRemoveFromCollection(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+	var form CollectionEntryForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.collections.Remove(collection.ID, form.SnippetID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Removed from the collection")
+	http.Redirect(w, r, fmt.Sprintf("/collection/%d", collection.ID), http.StatusSeeOther)
+}
+
+// POST /collection/:id/move puts an entry of the owner's collection at another position
+func (app *application) HandleMoveInCollection(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+	var form CollectionEntryForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil || form.Position < 1 {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.collections.Move(collection.ID, form.SnippetID, form.Position)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/collection/%d#snippet-%d", collection.ID, form.SnippetID), http.StatusSeeOther)
+}
+
+// user/stars lists the snippets the caller starred
+func (app *application) HandleUserStars(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.stars.List(app.AuthenticatedUserID(r))
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	data := app.NewTemplateData(r)
+	data.Snippets = snippets
+	app.Render(w, http.StatusOK, "stars.tmpl", data)
+}
+
+// user/snippets lists the caller's snippets, expired ones included
+func (app *application) HandleUserSnippets(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.Owned(app.AuthenticatedUserID(r))
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	data := app.NewTemplateData(r)
+	data.Snippets = snippets
+	data.ExpiredDays = int(app.expiredRetention.Hours() / 24)
+	app.Render(w, http.StatusOK, "snippets.tmpl", data)
+}
+
+// POST /snippet/restore/:id
+func (app *application) HandleRestoreSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	// Restore only matches the caller's own snippets, so a stranger's id is a 404 too
+	err := app.snippets.Restore(id, app.AuthenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.sessionManager.Put(r.Context(), "flash", "Snippet restored")
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+}
+
+type SnippetLanguageForm struct {
+	File     string `form:"file"`     // Name of the file
+	Language string `form:"language"` // "" for plain text
+}
+
+type SnippetUnlockForm struct {
+	Passphrase          string `form:"passphrase"`
+	Token               string `form:"token"` // Share token, when unlocking through /s/:token
+	validator.Validator `form:"-"`
+}
+
+// Failed passphrases allowed per snippet within unlockWindow
+const (
+	unlockAttempts = 5
+	unlockWindow   = 15 * time.Minute
+)
+
+// POST /snippet/unlock/:id checks a snippet passphrase and remembers the
+// unlock in the session. Failures are throttled per snippet.
+func (app *application) HandleUnlockSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
 		app.NotFound(w)
 		return
 	}
-	// Use SnippetModel's Get
+	var form SnippetUnlockForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	snippet, next, ok := app.postedSnippet(w, r, id, form.Token)
+	if !ok {
+		return
+	}
+
+	if wait := app.unlockThrottle.Wait(id); wait > 0 {
+		form.AddNonFieldError(fmt.Sprintf("Too many wrong passphrases, try again in %d minutes", int(wait.Minutes())+1))
+		app.renderUnlock(w, http.StatusTooManyRequests, r, snippet, form)
+		return
+	}
+	form.CheckField(validator.NotBlank(form.Passphrase), "passphrase", "This field cannot be blank")
+	if !form.Valid8() {
+		app.renderUnlock(w, http.StatusUnprocessableEntity, r, snippet, form)
+		return
+	}
+	err = app.snippets.Unlock(id, form.Passphrase)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCreds) {
+			app.unlockThrottle.Fail(id)
+			form.AddNonFieldError("Wrong passphrase")
+			app.renderUnlock(w, http.StatusUnprocessableEntity, r, snippet, form)
+		} else if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	app.unlockThrottle.Reset(id)
+	app.RememberUnlock(r, id)
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// postedSnippet loads the snippet a form on its page was posted for, or one of
+// whose files was requested with ?token= (see serveFile). The same
+// rules as visibleSnippet apply, except that a matching share token also lets
+// visitors in to an unlisted snippet. It returns the snippet's page URL to
+// redirect back to, and writes the 404 itself on failure.
+func (app *application) postedSnippet(w http.ResponseWriter, r *http.Request, id int, token string) (*models.Snippet, string, bool) {
 	snippet, err := app.snippets.Get(id)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
@@ -42,22 +1118,151 @@ func (app *application) HandleViewSnippet(w http.ResponseWriter, r *http.Request
 		} else {
 			app.ServerError(w, err)
 		}
+		return nil, "", false
+	}
+	shared := snippet.Visibility == models.Unlisted && token != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(snippet.ShareToken)) == 1
+	if shared {
+		return snippet, "/s/" + snippet.ShareToken, true
+	}
+	if snippet.Visibility != models.Public && !app.IsSnippetOwner(r, snippet) {
+		app.NotFound(w)
+		return nil, "", false
+	}
+	return snippet, fmt.Sprintf("/snippet/view/%d", id), true
+}
+
+type SnippetRevealForm struct {
+	Token string `form:"token"` // Share token, when revealing through /s/:token
+}
+
+// POST /snippet/reveal/:id uses up one view of a snippet with a view limit and
+// shows it. The content is rendered straight into the response rather than
+// behind a redirect, since the view it cost cannot be spent twice.
+func (app *application) HandleRevealSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
 		return
 	}
-	//  Retrieve the flash value from the context
-	// flash := app.sessionManager.PopString(r.Context(), "flash")
+	var form SnippetRevealForm
+	err := app.DecodePostForm(r, &form)
+	if err != nil {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	snippet, next, ok := app.postedSnippet(w, r, id, form.Token)
+	if !ok {
+		return
+	}
+	if !app.IsUnlocked(r, snippet) || !app.CountsViews(r, snippet) {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	// Load the history first: the last view deletes it along with the snippet
+	revisions, err := app.snippets.Revisions(id)
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	left, err := app.snippets.Consume(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			// Somebody else took the last view
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	snippet.ViewsLeft = left
+	app.countView(r, snippet)
+	data, err := app.snippetData(r, snippet, revisions, revisions[len(revisions)-1])
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	data.ShareToken = form.Token
+	data.Burnt = left == 0
+	app.Render(w, http.StatusOK, "view.tmpl", data)
+}
+
+// renderReveal shows the interstitial that asks before using up a view.
+func (app *application) renderReveal(w http.ResponseWriter, r *http.Request, snippet *models.Snippet, shareToken string) {
 	data := app.NewTemplateData(r)
 	data.Snippet = snippet
-	// Pass flash to the template
-	// data.Flash = flash
-	app.Render(w, http.StatusOK, "view.tmpl", data)
+	data.Form = SnippetRevealForm{Token: shareToken}
+	app.Render(w, http.StatusOK, "reveal.tmpl", data)
+}
+
+// renderUnlock shows the passphrase form in place of a locked snippet.
+func (app *application) renderUnlock(w http.ResponseWriter, status int, r *http.Request, snippet *models.Snippet, form SnippetUnlockForm) {
+	form.Passphrase = ""
+	data := app.NewTemplateData(r)
+	data.Snippet = snippet
+	data.Form = form
+	app.Render(w, status, "unlock.tmpl", data)
+}
+
+// visibleSnippet loads a snippet the caller may open by id: any public one,
+// or their own unlisted and private ones. Unlisted snippets are otherwise only
+// reachable through /s/:token. It writes the 404 itself and returns false on failure.
+func (app *application) visibleSnippet(w http.ResponseWriter, r *http.Request, id int) (*models.Snippet, bool) {
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return nil, false
+	}
+	// A 404 rather than a 403, so hidden snippet ids cannot be probed
+	if snippet.Visibility != models.Public && !app.IsSnippetOwner(r, snippet) {
+		app.NotFound(w)
+		return nil, false
+	}
+	return snippet, true
+}
+
+// ownedSnippet loads the :id snippet for an owner-only action.
+// It writes the 404 / 403 response itself and returns false if the caller should stop.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return nil, false
+	}
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return nil, false
+	}
+	if !app.IsSnippetOwner(r, snippet) {
+		app.ClientError(w, http.StatusForbidden)
+		return nil, false
+	}
+	return snippet, true
 }
 
 // snippet/create
 func (app *application) HandleSnippetForm(w http.ResponseWriter, r *http.Request) {
+	role, err := app.users.Role(app.AuthenticatedUserID(r))
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
 	data := app.NewTemplateData(r)
+	data.ExpiryOptions = app.expiry.Allowed(role)
+	data.Languages = highlight.Languages
 	data.Form = SnippetCreateForm{
-		Expires: 365,
+		Files:      []SnippetFileForm{{}},
+		Expires:    app.expiry.DefaultFor(role),
+		Visibility: string(models.Public),
 	}
 	app.Render(w, http.StatusOK, "create.tmpl", data)
 }
@@ -82,19 +1287,86 @@ func (app *application) HandleCreateSnippet(w http.ResponseWriter, r *http.Reque
 	// Title not blank and < 100 chars long. Add a message if so.
 	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
 	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be longer than 100 chars")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PermittedInt(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7, or 365")
+	role, err := app.users.Role(app.AuthenticatedUserID(r))
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	form.Files = compactFiles(form.Files)
+	if form.AddFile && len(form.Files) < models.MaxFiles {
+		// Not a submission yet: show the form again with one more file
+		form.Files = append(form.Files, SnippetFileForm{})
+		form.FieldErrors = nil
+		form.Passphrase = ""
+		data := app.NewTemplateData(r)
+		data.ExpiryOptions = app.expiry.Allowed(role)
+		data.Languages = highlight.Languages
+		data.Form = form
+		app.Render(w, http.StatusOK, "create.tmpl", data)
+		return
+	}
+	form.CheckField(len(form.Files) <= models.MaxFiles, "files", fmt.Sprintf("No more than %d files", models.MaxFiles))
+	names := map[string]bool{}
+	for i := range form.Files {
+		f := &form.Files[i]
+		key := fmt.Sprintf("files.%d", i)
+		if f.Name = strings.TrimSpace(f.Name); f.Name == "" {
+			f.Name = defaultFileName(i)
+		}
+		form.CheckField(validator.NotBlank(f.Content), key, "The content cannot be blank")
+		form.CheckField(validator.MaxChars(f.Name, models.MaxFileNameLength), key, fmt.Sprintf("File names cannot be longer than %d chars", models.MaxFileNameLength))
+		form.CheckField(validator.Matches(f.Name, validator.FileNameRegex), key, "File names may only contain letters, digits and . _ -")
+		form.CheckField(!names[f.Name], key, "Another file already has this name")
+		form.CheckField(f.Language == "" || validator.PermittedValue(f.Language, highlight.Names()...), key, "The language must be one of the listed languages")
+		names[f.Name] = true
+	}
+	_, ok := app.expiry.Lookup(form.Expires, role)
+	form.CheckField(ok, "expires", "This field must be one of the offered lifetimes")
+	form.CheckField(validator.PermittedValue(models.Visibility(form.Visibility), models.Visibilities...), "visibility", "This field must be public, unlisted or private")
+	form.CheckField(form.MaxViews >= 0 && form.MaxViews <= models.MaxViewLimit, "max_views", fmt.Sprintf("This field must be between 0 and %d", models.MaxViewLimit))
+	if form.Passphrase != "" {
+		form.CheckField(validator.MinChars(form.Passphrase, 8), "passphrase", "This field must be at least 8 chars long")
+		// bcrypt only looks at the first 72 bytes
+		form.CheckField(len(form.Passphrase) <= 72, "passphrase", "This field cannot be longer than 72 bytes")
+	}
+	tags := models.ParseTags(form.Tags)
+	form.CheckField(len(tags) <= models.MaxTags, "tags", fmt.Sprintf("No more than %d tags", models.MaxTags))
+	for _, tag := range tags {
+		form.CheckField(validator.MaxChars(tag, models.MaxTagLength), "tags", fmt.Sprintf("Tags cannot be longer than %d chars", models.MaxTagLength))
+		form.CheckField(validator.Matches(tag, validator.TagRegex), "tags", "Tags may only contain letters, digits and + # . _ -")
+	}
 
 	if !form.Valid8() {
 		// if len(form.FieldErrors) > 0 {
+		form.Passphrase = "" // Never echoed back into the page
 		data := app.NewTemplateData(r)
+		data.ExpiryOptions = app.expiry.Allowed(role)
+		data.Languages = highlight.Languages
 		data.Form = form
 		app.Render(w, http.StatusUnprocessableEntity, "create.tmpl", data)
 		// fmt.Fprint(w, fieldErrors)
 		return
 	}
 
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	files := make([]models.File, len(form.Files))
+	for i, f := range form.Files {
+		files[i] = models.File{Name: f.Name, Content: f.Content, Language: f.Language}
+		// No language picked: guess one, the owner can correct it on the view page
+		if f.Language == "" {
+			files[i].Language, files[i].LanguageConfidence = highlight.DetectFile(f.Name, f.Content)
+		}
+	}
+	id, err := app.snippets.Insert(models.NewSnippet{
+		Title:      form.Title,
+		Files:      files,
+		Expires:    form.Expires,
+		Role:       role,
+		OwnerID:    app.AuthenticatedUserID(r),
+		Tags:       tags,
+		Visibility: models.Visibility(form.Visibility),
+		Passphrase: form.Passphrase,
+		MaxViews:   form.MaxViews,
+	})
 	if err != nil {
 		app.ServerError(w, err)
 		return
@@ -104,6 +1376,117 @@ func (app *application) HandleCreateSnippet(w http.ResponseWriter, r *http.Reque
 	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
 }
 
+// Lines of unchanged context around each unified hunk
+const diffContext = 3
+
+// SnippetDiff holds everything diff.tmpl needs to compare two revisions,
+// which may belong to two different snippets (e.g. a fork and its parent).
+type SnippetDiff struct {
+	From          *models.Revision
+	To            *models.Revision
+	FromRevisions []*models.Revision
+	ToRevisions   []*models.Revision
+	WithID        int    // Other snippet, 0 when comparing revisions of one snippet
+	Layout        string // "unified" or "split"
+	Changed       bool
+	Hunks         []diff.Hunk
+	Rows          []diff.Row
+}
+
+// /snippet/diff/:id?from=a&to=b compares revisions a and b of one snippet.
+// With &with=otherID, "to" is a revision of the other snippet instead.
+// &layout=split switches from the unified to the side-by-side view.
+func (app *application) HandleDiffSnippet(w http.ResponseWriter, r *http.Request) {
+	id, ok := ParamInt(r, "id")
+	if !ok {
+		app.NotFound(w)
+		return
+	}
+	withID, ok := QueryInt(r, "with", id)
+	if !ok {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	layout := r.URL.Query().Get("layout")
+	if layout != "split" {
+		layout = "unified"
+	}
+	for _, sid := range []int{id, withID} {
+		snippet, ok := app.visibleSnippet(w, r, sid)
+		if !ok {
+			return
+		}
+		if !app.IsUnlocked(r, snippet) {
+			app.renderUnlock(w, http.StatusOK, r, snippet, SnippetUnlockForm{})
+			return
+		}
+		if app.CountsViews(r, snippet) {
+			app.renderReveal(w, r, snippet, "")
+			return
+		}
+	}
+
+	fromRevisions, err := app.snippets.Revisions(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.NotFound(w)
+		} else {
+			app.ServerError(w, err)
+		}
+		return
+	}
+	toRevisions := fromRevisions
+	if withID != id {
+		toRevisions, err = app.snippets.Revisions(withID)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.NotFound(w)
+			} else {
+				app.ServerError(w, err)
+			}
+			return
+		}
+	}
+
+	// By default show what the latest edit changed, or latest against latest for two snippets
+	defaultFrom := len(fromRevisions)
+	if withID == id && defaultFrom > 1 {
+		defaultFrom--
+	}
+	from, okFrom := QueryInt(r, "from", defaultFrom)
+	to, okTo := QueryInt(r, "to", len(toRevisions))
+	if !okFrom || !okTo {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	if from > len(fromRevisions) || to > len(toRevisions) {
+		app.NotFound(w)
+		return
+	}
+
+	d := &SnippetDiff{
+		From:          fromRevisions[from-1],
+		To:            toRevisions[to-1],
+		FromRevisions: fromRevisions,
+		ToRevisions:   toRevisions,
+		Layout:        layout,
+	}
+	if withID != id {
+		d.WithID = withID
+	}
+	lines := diff.Lines(d.From.Content, d.To.Content)
+	d.Changed = diff.Changed(lines)
+	if layout == "split" {
+		d.Rows = diff.SideBySide(lines)
+	} else {
+		d.Hunks = diff.Unified(lines, diffContext)
+	}
+
+	data := app.NewTemplateData(r)
+	data.Diff = d
+	app.Render(w, http.StatusOK, "diff.tmpl", data)
+}
+
 type UserSignupForm struct {
 	Name                string `form:"name"`
 	Email               string `form:"email"`
@@ -233,18 +1616,89 @@ func (app *application) HandleHome(w http.ResponseWriter, r *http.Request) {
 	// 	return
 	// }
 	// panic("oops! something went wrong") // deliverate panic
-	snippets, err := app.snippets.Latest10()
-	if err != nil {
-		app.ServerError(w, err)
+	data, ok := app.listSnippets(w, r, "")
+	if !ok {
 		return
 	}
-	data := app.NewTemplateData(r)
-	data.Snippets = snippets
 	// Use render helper
 	fmt.Printf("Year: %+v\n", data.CurrentYear)
 	app.Render(w, http.StatusOK, "home.tmpl", data)
 }
 
+// listSnippets loads the page of snippets picked by
+// ?sort=newest|oldest|expiring|views&cursor=..., optionally only those tagged tag.
+// On failure it writes the error response itself and returns false.
+func (app *application) listSnippets(w http.ResponseWriter, r *http.Request, tag string) (*templateData, bool) {
+	sort, ok := models.ParseSort(r.URL.Query().Get("sort"))
+	if !ok {
+		app.ClientError(w, http.StatusBadRequest)
+		return nil, false
+	}
+	cursor := r.URL.Query().Get("cursor")
+	page, err := app.snippets.Page(models.ListOptions{Sort: sort, Cursor: cursor, Size: app.pageSize, Tag: tag})
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			app.ClientError(w, http.StatusBadRequest)
+		} else {
+			app.ServerError(w, err)
+		}
+		return nil, false
+	}
+	data := app.NewTemplateData(r)
+	data.Snippets = page.Snippets
+	data.Sort = string(sort)
+	data.NextCursor = page.Next
+	data.IsFirstPage = cursor == ""
+	return data, true
+}
+
+// /tag/:name lists the live snippets carrying a tag
+func (app *application) HandleTag(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	tag := params.ByName("name")
+	if !validator.Matches(tag, validator.TagRegex) {
+		app.NotFound(w)
+		return
+	}
+	data, ok := app.listSnippets(w, r, tag)
+	if !ok {
+		return
+	}
+	data.Tag = tag
+	app.Render(w, http.StatusOK, "tag.tmpl", data)
+}
+
+// /tags shows every tag in use, sized by how many snippets carry it
+func (app *application) HandleTags(w http.ResponseWriter, r *http.Request) {
+	cloud, err := app.snippets.TagCloud()
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	data := app.NewTemplateData(r)
+	data.TagCloud = cloud
+	app.Render(w, http.StatusOK, "tags.tmpl", data)
+}
+
+// /search?q=...&page=n
+func (app *application) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	page, ok := QueryInt(r, "page", 1)
+	if !ok {
+		app.ClientError(w, http.StatusBadRequest)
+		return
+	}
+	results, err := app.snippets.Search(q, page, app.pageSize)
+	if err != nil {
+		app.ServerError(w, err)
+		return
+	}
+	data := app.NewTemplateData(r)
+	data.Query = q
+	data.Search = results
+	app.Render(w, http.StatusOK, "search.tmpl", data)
+}
+
 // /snippet/view?id=123
 // func (app *application) HandleViewSnippet(w http.ResponseWriter, r *http.Request) {
 // 	params := httprouter.ParamsFromContext(r.Context())
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/iam-vl/snbox/internal/models/mocks"
+)
+
+func TestViewSnippet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{"Valid ID", "/snippet/view/1", http.StatusOK, "An old silent pond..."},
+		{"Non-existent ID", "/snippet/view/2", http.StatusNotFound, ""},
+		{"Negative ID", "/snippet/view/-1", http.StatusNotFound, ""},
+		{"Decimal ID", "/snippet/view/1.23", http.StatusNotFound, ""},
+		{"String ID", "/snippet/view/foo", http.StatusNotFound, ""},
+		{"Empty ID", "/snippet/view/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body does not contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestUserSignup(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	validToken := ts.csrfToken(t, "/user/signup")
+
+	tests := []struct {
+		name         string
+		userName     string
+		userEmail    string
+		userPassword string
+		csrfToken    string
+		wantCode     int
+		wantBody     string
+	}{
+		{"Valid submission", "Bob", "bob@example.com", "validPa$$word", validToken, http.StatusSeeOther, ""},
+		{"Invalid CSRF token", "Bob", "bob@example.com", "validPa$$word", "wrongToken", http.StatusBadRequest, ""},
+		{"Empty name", "", "bob@example.com", "validPa$$word", validToken, http.StatusUnprocessableEntity, "This field cannot be blank"},
+		{"Invalid email", "Bob", "bob@example.", "validPa$$word", validToken, http.StatusUnprocessableEntity, "This field must be a valid email"},
+		{"Short password", "Bob", "bob@example.com", "pa$$", validToken, http.StatusUnprocessableEntity, "This field must be at least 8 chars long"},
+		{"Duplicate email", "Bob", "dupe@example.com", "validPa$$word", validToken, http.StatusUnprocessableEntity, "Email address already in use"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("name", tt.userName)
+			form.Add("email", tt.userEmail)
+			form.Add("password", tt.userPassword)
+			form.Add("csrf_token", tt.csrfToken)
+			code, _, body := ts.postForm(t, "/user/signup", form)
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body does not contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetCreateForm(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		code, header, _ := ts.get(t, "/snippet/create")
+		if code != http.StatusSeeOther {
+			t.Errorf("got status %d; want %d", code, http.StatusSeeOther)
+		}
+		if got := header.Get("Location"); got != "/user/login" {
+			t.Errorf("got redirect to %q; want /user/login", got)
+		}
+	})
+	t.Run("Authenticated", func(t *testing.T) {
+		ts.login(t, "alice@example.com")
+		code, _, body := ts.get(t, "/snippet/create")
+		if code != http.StatusOK {
+			t.Errorf("got status %d; want %d", code, http.StatusOK)
+		}
+		if !strings.Contains(body, `<form action="/snippet/create" method="POST"`) {
+			t.Error("body does not contain the create form")
+		}
+	})
+}
+
+func TestEditSnippet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	ts.login(t, "alice@example.com")
+	token := ts.csrfToken(t, "/snippet/edit/1")
+
+	tests := []struct {
+		name      string
+		urlPath   string
+		content   string
+		wantCode  int
+		wantFlash string
+	}{
+		{"New content", "/snippet/edit/1", "A frog jumps in", http.StatusSeeOther, "Snippet saved as revision 2"},
+		{"Unchanged", "/snippet/edit/1", "An old silent pond...", http.StatusSeeOther, "No changes to save"},
+		{"Non-existent ID", "/snippet/edit/2", "A frog jumps in", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "An old silent pond")
+			form.Add("content", tt.content)
+			form.Add("csrf_token", token)
+			code, _, _ := ts.postForm(t, tt.urlPath, form)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+			if tt.wantFlash == "" {
+				return
+			}
+			_, _, body := ts.get(t, "/snippet/view/1")
+			if !strings.Contains(body, tt.wantFlash) {
+				t.Errorf("body does not contain flash %q", tt.wantFlash)
+			}
+		})
+	}
+}
+
+func TestViewSnippetVisibility(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name     string
+		email    string // Logged-in user, "" for an anonymous visitor
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{"Public, anonymous", "", "/snippet/view/1", http.StatusOK, "An old silent pond..."},
+		// Hidden snippets are a 404, not a 403, so their ids cannot be probed
+		{"Private, anonymous", "", "/snippet/view/3", http.StatusNotFound, ""},
+		{"Private, another user", "bob@example.com", "/snippet/view/3", http.StatusNotFound, ""},
+		{"Private, owner", "alice@example.com", "/snippet/view/3", http.StatusOK, "Over the wintry forest..."},
+		{"Unlisted by id, anonymous", "", "/snippet/view/4", http.StatusNotFound, ""},
+		{"Unlisted by id, another user", "bob@example.com", "/snippet/view/4", http.StatusNotFound, ""},
+		{"Unlisted by id, owner", "alice@example.com", "/snippet/view/4", http.StatusOK, "A world of dew..."},
+		{"Share link", "", "/s/" + mocks.MockShareToken, http.StatusOK, "A world of dew..."},
+		{"Share link, another user", "bob@example.com", "/s/" + mocks.MockShareToken, http.StatusOK, "A world of dew..."},
+		{"Wrong share token", "", "/s/wrong-token", http.StatusNotFound, ""},
+		{"Share token in the wrong case", "", "/s/" + strings.ToUpper(mocks.MockShareToken), http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A fresh cookie jar per case, so each runs as its own visitor
+			ts := newTestServer(t, app.routes())
+			if tt.email != "" {
+				ts.login(t, tt.email)
+			}
+			code, _, body := ts.get(t, tt.urlPath)
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body does not contain %q", tt.wantBody)
+			}
+		})
+	}
+}
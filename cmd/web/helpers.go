@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/go-playground/form/v4"
+	"github.com/iam-vl/snbox/internal/models"
+	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/nosurf"
 )
 
@@ -20,11 +24,84 @@ func (app *application) IsAuthenticated(r *http.Request) bool {
 	return isAuth
 }
 
+// Id of the logged-in user, or 0 for anonymous visitors
+func (app *application) AuthenticatedUserID(r *http.Request) int {
+	if !app.IsAuthenticated(r) {
+		return 0
+	}
+	return app.sessionManager.GetInt(r.Context(), "authenticatedUserId")
+}
+
+// The user who created a snippet owns it and may edit or delete it.
+// Snippets from before owners were recorded have no owner.
+func (app *application) IsSnippetOwner(r *http.Request, snippet *models.Snippet) bool {
+	userID := app.AuthenticatedUserID(r)
+	return userID != 0 && snippet.OwnerID == userID
+}
+
+// Only the user who created a collection may change it.
+func (app *application) IsCollectionOwner(r *http.Request, collection *models.Collection) bool {
+	userID := app.AuthenticatedUserID(r)
+	return userID != 0 && collection.OwnerID == userID
+}
+
+// Key of the []int of snippet ids unlocked with their passphrase in this session
+const unlockedSnippetsKey = "unlockedSnippets"
+
+// IsUnlocked reports whether the caller may read a snippet's content: it has
+// no passphrase, the caller owns it, or they unlocked it earlier in the session.
+func (app *application) IsUnlocked(r *http.Request, snippet *models.Snippet) bool {
+	if !snippet.Locked || app.IsSnippetOwner(r, snippet) {
+		return true
+	}
+	unlocked, _ := app.sessionManager.Get(r.Context(), unlockedSnippetsKey).([]int)
+	return slices.Contains(unlocked, snippet.ID)
+}
+
+// CountsViews reports whether showing a snippet to the caller uses up one of
+// its limited views. The owner's own visits are free.
+func (app *application) CountsViews(r *http.Request, snippet *models.Snippet) bool {
+	return snippet.ViewsLeft > 0 && !app.IsSnippetOwner(r, snippet)
+}
+
+// RememberUnlock records a successful unlock in the session.
+func (app *application) RememberUnlock(r *http.Request, id int) {
+	unlocked, _ := app.sessionManager.Get(r.Context(), unlockedSnippetsKey).([]int)
+	if !slices.Contains(unlocked, id) {
+		app.sessionManager.Put(r.Context(), unlockedSnippetsKey, append(unlocked, id))
+	}
+}
+
+// Read a positive integer route parameter, such as the :id in /snippet/view/:id
+func ParamInt(r *http.Request, name string) (int, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+	n, err := strconv.Atoi(params.ByName(name))
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// Read a positive integer from the query string, such as ?from=2.
+// Returns def when the parameter is absent and false when it is malformed.
+func QueryInt(r *http.Request, name string, def int) (int, bool) {
+	val := r.URL.Query().Get(name)
+	if val == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
 func (app *application) NewTemplateData(r *http.Request) *templateData {
 	return &templateData{
 		CurrentYear: time.Now().Year(),
 		Flash:       app.sessionManager.PopString(r.Context(), "flash"),
 		IsAuth:      app.IsAuthenticated(r), // Added the auth status to the templ data
+		UserID:      app.AuthenticatedUserID(r),
 		CSRFToken:   nosurf.Token(r),
 	}
 }
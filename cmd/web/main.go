@@ -1,21 +1,31 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/alexedwards/scs/mysqlstore"
+	"github.com/alexedwards/scs/postgresstore"
+	"github.com/alexedwards/scs/sqlite3store"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
 	_ "github.com/go-sql-driver/mysql" // Not using it, but need the init() function
 	"github.com/iam-vl/snbox/internal/models"
+	"github.com/iam-vl/snbox/migrations"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" driver
+	_ "modernc.org/sqlite"             // Registers the "sqlite" driver
 )
 
 const (
@@ -23,31 +33,84 @@ const (
 )
 
 type application struct {
-	errorLog       *log.Logger
-	infoLog        *log.Logger
-	snippets       *models.SnippetModel
-	users          *models.UserModel
-	templateCache  map[string]*template.Template
-	formDecoder    *form.Decoder
-	sessionManager *scs.SessionManager
+	errorLog         *log.Logger
+	infoLog          *log.Logger
+	snippets         models.SnippetStore
+	users            models.UserStore
+	comments         models.CommentStore
+	stars            models.StarStore
+	collections      models.CollectionStore
+	templateCache    map[string]*template.Template
+	formDecoder      *form.Decoder
+	sessionManager   *scs.SessionManager
+	trashRetention   time.Duration
+	expiredRetention time.Duration // How long expired snippets stay on the dashboard
+	pageSize         int
+	expiry           *models.ExpiryPolicy
+	unlockThrottle   *throttle // Failed passphrase attempts per snippet
+	views            *viewCounter
 }
 
 func main() {
 
 	port := flag.String("port", ":1111", "Server port")
-	dsnText := fmt.Sprintf("web:%s@/snbox?parseTime=true&allowNativePasswords=true", pwd)
-	dsn := flag.String("dsn", dsnText, "sb_mysql_datasource")
+	dbDriver := flag.String("db-driver", "mysql", "Database driver: mysql, sqlite or postgres")
+	dsn := flag.String("dsn", "", "Datasource name (defaults depend on -db-driver)")
+	pageSize := flag.Int("page-size", 10, "Snippets per page in listings")
+	trashRetention := flag.Duration("trash-retention", models.DefaultTrashRetention, "How long deleted snippets stay restorable")
+	expiredRetention := flag.Duration("expired-retention", models.DefaultExpiredRetention, "How long expired snippets stay listed on their owner's dashboard before they are purged")
+	reaperInterval := flag.Duration("reaper-interval", 10*time.Minute, "How often expired snippets and sessions are purged")
+	reaperBatch := flag.Int("reaper-batch", 500, "Max rows the reaper deletes per statement")
+	reaperDryRun := flag.Bool("reaper-dry-run", false, "Log what the reaper would delete without deleting it")
+	viewFlushInterval := flag.Duration("view-flush-interval", 30*time.Second, "How often counted snippet views are written to the database")
+	expiryOptions := flag.String("expiry-options", "365d,7d,1d", "Snippet lifetimes offered on the create form: m, h, d or w units, or never")
+	expiryDefault := flag.String("expiry-default", "365d", "Lifetime preselected on the create form")
+	expiryMax := flag.String("expiry-max", "", "Longest lifetime per user role, e.g. user=7d,admin=never")
+	autoMigrate := flag.Bool("auto-migrate", false, "Apply pending schema migrations before starting the server")
 	flag.Parse() // can use port as a flag
 
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 
-	db, err := openDb(*dsn)
+	if *reaperInterval <= 0 || *reaperBatch < 1 || *pageSize < 1 || *viewFlushInterval <= 0 {
+		errorLog.Fatal("-reaper-interval, -reaper-batch, -page-size and -view-flush-interval must be positive")
+	}
+
+	expiry, err := models.ParseExpiryPolicy(*expiryOptions, *expiryDefault, *expiryMax)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	dialect, err := models.ParseDialect(*dbDriver)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+	if *dsn == "" {
+		*dsn = defaultDsn(dialect)
+	}
+
+	db, err := openDb(dialect, *dsn)
 	if err != nil {
 		errorLog.Fatal(err)
 	}
 	defer db.Close()
 
+	// `web migrate up|down|status` manages the schema and exits
+	migrationModel := &models.MigrationModel{DB: db, Dialect: dialect, Files: migrations.Files}
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(os.Stdout, migrationModel, flag.Args()[1:]); err != nil {
+			errorLog.Fatal(err)
+		}
+		return
+	}
+	if *autoMigrate {
+		done, err := migrationModel.Up()
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		infoLog.Printf("Applied %d migration(s)", len(done))
+	}
+
 	// templateCache, err := NewTemplateCache()
 	templateCache, err := NewTemplateCache3()
 	// templateCache, err := NTC2() // before
@@ -61,19 +124,38 @@ func main() {
 	formDecoder := form.NewDecoder()
 	// Configure a sesh manager
 	sessionManager := scs.New()
-	sessionManager.Store = mysqlstore.New(db)
+	sessionManager.Store = newSessionStore(dialect, db)
 	sessionManager.Lifetime = 12 * time.Hour
 	// Serve over https
 	sessionManager.Cookie.Secure = true
 
 	app := &application{
-		errorLog:       errorLog,
-		infoLog:        infoLog,
-		snippets:       &models.SnippetModel{DB: db},
-		users:          &models.UserModel{DB: db},
-		templateCache:  templateCache,
-		formDecoder:    formDecoder,
-		sessionManager: sessionManager,
+		errorLog:         errorLog,
+		infoLog:          infoLog,
+		snippets:         &models.SnippetModel{DB: db, Dialect: dialect, TrashRetention: *trashRetention, ExpiredRetention: *expiredRetention, Expiry: expiry},
+		users:            &models.UserModel{DB: db, Dialect: dialect},
+		comments:         &models.CommentModel{DB: db, Dialect: dialect},
+		stars:            &models.StarModel{DB: db, Dialect: dialect},
+		collections:      &models.CollectionModel{DB: db, Dialect: dialect},
+		templateCache:    templateCache,
+		formDecoder:      formDecoder,
+		sessionManager:   sessionManager,
+		trashRetention:   *trashRetention,
+		expiredRetention: *expiredRetention,
+		pageSize:         *pageSize,
+		expiry:           expiry,
+		unlockThrottle:   newThrottle(unlockAttempts, unlockWindow),
+	}
+	app.views = newViewCounter(app.snippets, *viewFlushInterval, errorLog)
+	// The reaper replaces the session stores' own cleanup goroutines (see newSessionStore)
+	reaper := &reaper{
+		snippets:  app.snippets,
+		sessions:  &models.SessionModel{DB: db, Dialect: dialect},
+		interval:  *reaperInterval,
+		batchSize: *reaperBatch,
+		dryRun:    *reaperDryRun,
+		infoLog:   infoLog,
+		errorLog:  errorLog,
 	}
 	// create somewhere to hold custom TLS settings
 	tlsConfig := &tls.Config{
@@ -94,17 +176,57 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	// Need to dereference a pointer
-	infoLog.Printf("Starting server on port: %s", *port)
-	// Use TLS
-	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	// err = srv.ListenAndServe()
-	// err := http.ListenAndServe(*port, mux) // legacy
-	errorLog.Fatal(err)
+	// Ctrl+C or SIGTERM starts a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background workers, waited for before the db is closed
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		reaper.Run(ctx)
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.views.Run(ctx)
+	}()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		// Need to dereference a pointer
+		infoLog.Printf("Starting server on port: %s", *port)
+		// Use TLS
+		serverErr <- srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
+		// err = srv.ListenAndServe()
+		// err := http.ListenAndServe(*port, mux) // legacy
+	}()
+
+	select {
+	case err = <-serverErr:
+		errorLog.Print(err)
+	case <-ctx.Done():
+		infoLog.Print("Shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err = srv.Shutdown(shutdownCtx)
+		if err != nil {
+			errorLog.Print(err)
+		}
+	}
+	stop()
+	wg.Wait()
+	// The server is down, so no more views come in: write out the last ones
+	app.views.Flush()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		db.Close()
+		os.Exit(1)
+	}
 }
 
-func openDb(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn) // Initializing connection pool
+func openDb(dialect models.Dialect, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(dialect.DriverName(), dsn) // Initializing connection pool
 	if err != nil {
 		return nil, err
 	}
@@ -116,3 +238,36 @@ func openDb(dsn string) (*sql.DB, error) {
 	fmt.Printf("Database: %+v\n", db)
 	return db, nil
 }
+
+// A single file next to the binary is all the SQLite mode needs.
+// Foreign keys are off by default in SQLite, and WAL + busy_timeout
+// let concurrent requests wait for the writer instead of failing.
+// _time_format=sqlite stores times as "2006-01-02 15:04:05-07:00" text,
+// which sorts and compares correctly as a string. _txlock=immediate takes
+// the write lock at BEGIN, standing in for the row locks of SELECT ... FOR UPDATE.
+const sqliteDsn = "file:snbox.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite&_txlock=immediate"
+
+func defaultDsn(dialect models.Dialect) string {
+	switch dialect {
+	case models.SQLite:
+		return sqliteDsn
+	case models.Postgres:
+		// Keyword/value form, so the password needs no URL escaping
+		return fmt.Sprintf("host=localhost user=web password=%s dbname=snbox sslmode=disable", pwd)
+	default:
+		return fmt.Sprintf("web:%s@/snbox?parseTime=true&allowNativePasswords=true", pwd)
+	}
+}
+
+// Pick the scs session store that matches the database.
+// Their cleanup goroutines are disabled: expired sessions are purged by the reaper.
+func newSessionStore(dialect models.Dialect, db *sql.DB) scs.Store {
+	switch dialect {
+	case models.SQLite:
+		return sqlite3store.NewWithCleanupInterval(db, 0)
+	case models.Postgres:
+		return postgresstore.NewWithCleanupInterval(db, 0)
+	default:
+		return mysqlstore.NewWithCleanupInterval(db, 0)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/iam-vl/snbox/internal/models"
+)
+
+// runMigrate implements `web migrate up|down|status`.
+// The -dsn used here needs a user allowed to run DDL (the "web" user is not).
+func runMigrate(w io.Writer, migrations *models.MigrationModel, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: web [flags] migrate up|down|status")
+	}
+	switch args[0] {
+	case "up":
+		done, err := migrations.Up()
+		for _, mg := range done {
+			fmt.Fprintf(w, "applied  %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Fprintln(w, "no pending migrations")
+		}
+	case "down":
+		mg, err := migrations.Down()
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				fmt.Fprintln(w, "no migrations to roll back")
+				return nil
+			}
+			return err
+		}
+		fmt.Fprintf(w, "reverted %04d_%s\n", mg.Version, mg.Name)
+	case "status":
+		all, err := migrations.Status()
+		if err != nil {
+			return err
+		}
+		for _, mg := range all {
+			state := "pending"
+			if mg.Applied != nil {
+				state = "applied " + HumanDate(*mg.Applied)
+			}
+			fmt.Fprintf(w, "%04d_%-30s %s\n", mg.Version, mg.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q (want up, down or status)", args[0])
+	}
+	return nil
+}
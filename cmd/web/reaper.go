@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/iam-vl/snbox/internal/models"
+)
+
+// reaper periodically hard deletes rows nobody can see any more:
+// expired snippets, snippets past their trash retention, and expired sessions.
+type reaper struct {
+	snippets  models.SnippetStore
+	sessions  *models.SessionModel
+	interval  time.Duration
+	batchSize int
+	dryRun    bool // Only count and log what would be deleted
+	infoLog   *log.Logger
+	errorLog  *log.Logger
+}
+
+// Run reaps once straight away and then every interval, until ctx is cancelled.
+func (rp *reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(rp.interval)
+	defer ticker.Stop()
+	for {
+		rp.reap(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rp *reaper) reap(ctx context.Context) {
+	jobs := []struct {
+		name string
+		run  func(limit int, dryRun bool) (int, error)
+	}{
+		{"expired snippets", rp.snippets.DeleteExpired},
+		{"trashed snippets", rp.snippets.PurgeTrash},
+		{"expired sessions", rp.sessions.DeleteExpired},
+	}
+	for _, job := range jobs {
+		n, err := rp.drain(ctx, job.run)
+		if err != nil {
+			rp.errorLog.Printf("reaper: %s: %v", job.name, err)
+		}
+		switch {
+		case rp.dryRun:
+			rp.infoLog.Printf("reaper (dry run): would remove %d %s", n, job.name)
+		case n > 0:
+			rp.infoLog.Printf("reaper: removed %d %s", n, job.name)
+		}
+	}
+}
+
+// drain runs a job batch after batch until a batch comes back short,
+// so a large backlog never turns into one long-running DELETE.
+// It stops between batches when ctx is cancelled.
+func (rp *reaper) drain(ctx context.Context, run func(limit int, dryRun bool) (int, error)) (int, error) {
+	total := 0
+	for ctx.Err() == nil {
+		n, err := run(rp.batchSize, rp.dryRun)
+		total += n
+		if err != nil || rp.dryRun || n < rp.batchSize {
+			return total, err
+		}
+	}
+	return total, nil
+}
@@ -39,6 +39,18 @@ func (app *application) routes() http.Handler {
 	// Unprotected routes
 	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.HandleHome)) // catch-all
 	router.Handler(http.MethodGet, "/snippet/view/:id", dynamic.ThenFunc(app.HandleViewSnippet))
+	router.Handler(http.MethodGet, "/s/:token", dynamic.ThenFunc(app.HandleSharedSnippet))
+	router.Handler(http.MethodPost, "/snippet/unlock/:id", dynamic.ThenFunc(app.HandleUnlockSnippet))
+	router.Handler(http.MethodPost, "/snippet/reveal/:id", dynamic.ThenFunc(app.HandleRevealSnippet))
+	router.Handler(http.MethodGet, "/snippet/view/:id/rev/:n", dynamic.ThenFunc(app.HandleViewRevision))
+	router.Handler(http.MethodGet, "/snippet/diff/:id", dynamic.ThenFunc(app.HandleDiffSnippet))
+	router.Handler(http.MethodGet, "/snippet/raw/:id/:name", dynamic.ThenFunc(app.HandleRawFile))
+	router.Handler(http.MethodGet, "/snippet/download/:id/:name", dynamic.ThenFunc(app.HandleDownloadFile))
+	router.Handler(http.MethodGet, "/search", dynamic.ThenFunc(app.HandleSearch))
+	router.Handler(http.MethodGet, "/collection/:id", dynamic.ThenFunc(app.HandleViewCollection))
+	router.Handler(http.MethodGet, "/c/:token", dynamic.ThenFunc(app.HandleSharedCollection))
+	router.Handler(http.MethodGet, "/tags", dynamic.ThenFunc(app.HandleTags))
+	router.Handler(http.MethodGet, "/tag/:name", dynamic.ThenFunc(app.HandleTag))
 	router.Handler(http.MethodGet, "/user/signup", dynamic.ThenFunc(app.HandleSignupForm))
 	router.Handler(http.MethodPost, "/user/signup", dynamic.ThenFunc(app.HandleSignupPost))
 	router.Handler(http.MethodGet, "/user/login", dynamic.ThenFunc(app.HandleLoginForm))
@@ -46,6 +58,26 @@ func (app *application) routes() http.Handler {
 	// Protected routes
 	router.Handler(http.MethodGet, "/snippet/create", protectedChain.ThenFunc(app.HandleSnippetForm))
 	router.Handler(http.MethodPost, "/snippet/create", protectedChain.ThenFunc(app.HandleCreateSnippet))
+	router.Handler(http.MethodGet, "/snippet/edit/:id", protectedChain.ThenFunc(app.HandleSnippetEditForm))
+	router.Handler(http.MethodPost, "/snippet/edit/:id", protectedChain.ThenFunc(app.HandleEditSnippet))
+	router.Handler(http.MethodPost, "/snippet/delete/:id", protectedChain.ThenFunc(app.HandleDeleteSnippet))
+	router.Handler(http.MethodPost, "/snippet/comments/:id", protectedChain.ThenFunc(app.HandleCreateComment))
+	router.Handler(http.MethodPost, "/snippet/comments/:id/lock", protectedChain.ThenFunc(app.HandleLockComments))
+	router.Handler(http.MethodGet, "/comment/edit/:id", protectedChain.ThenFunc(app.HandleCommentEditForm))
+	router.Handler(http.MethodPost, "/comment/edit/:id", protectedChain.ThenFunc(app.HandleEditComment))
+	router.Handler(http.MethodPost, "/comment/delete/:id", protectedChain.ThenFunc(app.HandleDeleteComment))
+	router.Handler(http.MethodPost, "/snippet/fork/:id", protectedChain.ThenFunc(app.HandleForkSnippet))
+	router.Handler(http.MethodPost, "/snippet/star/:id", protectedChain.ThenFunc(app.HandleStarSnippet))
+	router.Handler(http.MethodPost, "/snippet/language/:id", protectedChain.ThenFunc(app.HandleSetLanguage))
+	router.Handler(http.MethodPost, "/snippet/restore/:id", protectedChain.ThenFunc(app.HandleRestoreSnippet))
+	router.Handler(http.MethodGet, "/user/snippets", protectedChain.ThenFunc(app.HandleUserSnippets))
+	router.Handler(http.MethodGet, "/user/trash", protectedChain.ThenFunc(app.HandleTrash))
+	router.Handler(http.MethodGet, "/user/stars", protectedChain.ThenFunc(app.HandleUserStars))
+	router.Handler(http.MethodGet, "/user/collections", protectedChain.ThenFunc(app.HandleUserCollections))
+	router.Handler(http.MethodPost, "/user/collections", protectedChain.ThenFunc(app.HandleCreateCollection))
+	router.Handler(http.MethodPost, "/collection/:id/add", protectedChain.ThenFunc(app.HandleAddToCollection))
+	router.Handler(http.MethodPost, "/collection/:id/remove", protectedChain.ThenFunc(app.HandleRemoveFromCollection))
+	router.Handler(http.MethodPost, "/collection/:id/move", protectedChain.ThenFunc(app.HandleMoveInCollection))
 	router.Handler(http.MethodPost, "/user/logout", protectedChain.ThenFunc(app.HandleLogoutUser))
 
 	// router.HandlerFunc(http.MethodGet, "/", app.HandleHome) // catch-all
@@ -3,9 +3,14 @@ package main
 import (
 	"fmt"
 	"html/template"
+	"net/url"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/iam-vl/snbox/internal/highlight"
 	"github.com/iam-vl/snbox/internal/models"
 )
 
@@ -15,20 +20,168 @@ type templateData struct {
 	CurrentYear int
 	Snippet     *models.Snippet
 	Snippets    []*models.Snippet
+	Revision    *models.Revision   // Revision currently shown
+	Revisions   []*models.Revision // Full history, oldest first
+	IsOwner     bool               // The logged-in user owns .Snippet
+	Burnt       bool               // This view used up the last one and destroyed .Snippet
+	Starred     bool               // The logged-in user starred .Snippet
+	ShareToken  string             // Set when .Snippet was opened through its share link
+	Comments    []*models.Comment  // Discussion of .Snippet in reading order, less the threads in Review
+	Review      []ReviewPiece      // Main file cut around review threads on .Revision, nil if it has none
+	Comment     *models.Comment    // Comment being edited
+	Collection  *models.Collection
+	Collections []*models.Collection // The logged-in user's collections
+	Diff        *SnippetDiff
+	TrashDays   int // Retention window shown on the trash page
+	ExpiredDays int // How long the dashboard keeps listing expired snippets
+	Sort        string
+	NextCursor  string // Empty on the last page of a listing
+	IsFirstPage bool
+	Search      *models.SearchResults
+	Tag         string // Tag whose snippets are listed
+	TagCloud    []*models.TagCount
+	Query       string // Search box contents
 	Form        any
-	Flash       string // Flash message
-	IsAuth      bool   // Add to templ data
-	CSRFToken   string
+	// Lifetimes the create form offers to the logged-in user
+	ExpiryOptions []models.ExpiryOption
+	Languages     []highlight.Language // Choices of the language selector
+	Flash         string               // Flash message
+	IsAuth        bool                 // Add to templ data
+	UserID        int                  // Logged-in user, 0 when logged out
+	CSRFToken     string
+}
+
+// ReviewPiece is a stretch of the main file followed by the review threads
+// on its last line.
+type ReviewPiece struct {
+	Code    template.HTML
+	Threads []ReviewThread
+}
+
+// ReviewThread gathers the review comments on one line range, each followed
+// by its replies.
+type ReviewThread struct {
+	Anchor    string // Element id, e.g. "L10-L14"
+	LineStart int
+	LineEnd   int
+	Comments  []*models.Comment
+}
+
+// ShowsDiscussion reports whether the comments on .Snippet may be shown:
+// not under view-limited snippets, except to their owner.
+func (data *templateData) ShowsDiscussion() bool {
+	return data.Snippet != nil && !data.Burnt && (data.IsOwner || data.Snippet.ViewsLeft == 0)
+}
+
+// threadView is what the "comments" partial renders: a list of comments
+// within the page showing them. Inline is set for threads shown beside the code.
+type threadView struct {
+	*templateData
+	Thread []*models.Comment
+	Inline bool
 }
 
 func HumanDate(t time.Time) string {
+	if t.Equal(models.Never) {
+		return "Never"
+	}
 	// d := "02 Sep 2024"
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 	// return d
 }
 
+// termsRegexp matches any of the search terms, case-insensitively
+func termsRegexp(terms []string) *regexp.Regexp {
+	if len(terms) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// Highlight escapes text and wraps every occurrence of a search term in <mark>.
+// The text is escaped piece by piece here, so the result is safe to return as template.HTML.
+func Highlight(text string, terms []string) template.HTML {
+	rx := termsRegexp(terms)
+	if rx == nil {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range rx.FindAllStringIndex(text, -1) {
+		b.WriteString(template.HTMLEscapeString(text[last:loc[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(text[loc[0]:loc[1]]))
+		b.WriteString("</mark>")
+		last = loc[1]
+	}
+	b.WriteString(template.HTMLEscapeString(text[last:]))
+	return template.HTML(b.String())
+}
+
+// Excerpt cuts about n runes of text, centred on the first search term found.
+func Excerpt(text string, terms []string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	start := 0
+	if rx := termsRegexp(terms); rx != nil {
+		if loc := rx.FindStringIndex(text); loc != nil {
+			start = utf8.RuneCountInString(text[:loc[0]]) - n/3
+		}
+	}
+	runes := []rune(text)
+	if start < 0 {
+		start = 0
+	}
+	if start+n > len(runes) {
+		start = len(runes) - n
+	}
+	out := string(runes[start : start+n])
+	if start > 0 {
+		out = "…" + out
+	}
+	if start+n < len(runes) {
+		out += "…"
+	}
+	return out
+}
+
+// Syntax renders code with highlighting and line numbers anchored as
+// prefix+number. If highlighting fails the code is shown escaped, without
+// colours, rather than not at all.
+func Syntax(code, language, prefix string) template.HTML {
+	h, err := highlight.HTMLPrefixed(code, language, prefix)
+	if err != nil {
+		return template.HTML("<pre><code>" + template.HTMLEscapeString(code) + "</code></pre>")
+	}
+	return h
+}
+
 var functions = template.FuncMap{
 	"humanDate": HumanDate,
+	"sub":       func(a, b int) int { return a - b },
+	"add":       func(a, b int) int { return a + b },
+	"highlight": Highlight,
+	"excerpt":   Excerpt,
+	"syntax":    Syntax,
+	"langLabel": highlight.Label,
+	"percent":   func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	// Line anchors of file i: #L10 in the main file, #F2-L10 in the second
+	"linePrefix": func(i int) string {
+		if i == 0 {
+			return "L"
+		}
+		return fmt.Sprintf("F%d-L", i+1)
+	},
+	"thread": func(data *templateData, comments []*models.Comment, inline bool) threadView {
+		return threadView{data, comments, inline}
+	},
+	// Tags may hold "#" or "+", which must not leak into /tag/:name links unescaped
+	"pathEscape": url.PathEscape,
 }
 
 func NewTemplateCache() (map[string]*template.Template, error) {
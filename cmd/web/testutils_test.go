@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-playground/form/v4"
+	"github.com/iam-vl/snbox/internal/models"
+	"github.com/iam-vl/snbox/internal/models/mocks"
+)
+
+// The templates are read from ./ui/html, relative to the repository root
+// the server is normally started from.
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestApplication wires the handlers to the in-memory mocks.
+func newTestApplication(t *testing.T) *application {
+	templateCache, err := NewTemplateCache3()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionManager := scs.New()
+	sessionManager.Lifetime = 12 * time.Hour
+	sessionManager.Cookie.Secure = true
+
+	app := &application{
+		errorLog:         log.New(io.Discard, "", 0),
+		infoLog:          log.New(io.Discard, "", 0),
+		snippets:         &mocks.SnippetModel{},
+		users:            &mocks.UserModel{},
+		comments:         &mocks.CommentModel{},
+		stars:            &mocks.StarModel{},
+		collections:      &mocks.CollectionModel{},
+		templateCache:    templateCache,
+		formDecoder:      form.NewDecoder(),
+		sessionManager:   sessionManager,
+		trashRetention:   models.DefaultTrashRetention,
+		expiredRetention: models.DefaultExpiredRetention,
+		pageSize:         10,
+		expiry:           models.DefaultExpiryPolicy,
+		unlockThrottle:   newThrottle(unlockAttempts, unlockWindow),
+	}
+	app.views = newViewCounter(app.snippets, time.Minute, app.errorLog)
+	return app
+}
+
+// testServer keeps cookies between requests and does not follow redirects,
+// so that tests can check where a handler sends the user.
+type testServer struct {
+	*httptest.Server
+}
+
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	ts := httptest.NewTLSServer(h)
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Client().Jar = jar
+	ts.Client().CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	t.Cleanup(ts.Close)
+	return &testServer{ts}
+}
+
+func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, string) {
+	rs, err := ts.Client().Get(ts.URL + urlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readResponse(t, rs)
+}
+
+func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (int, http.Header, string) {
+	rs, err := ts.Client().PostForm(ts.URL+urlPath, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readResponse(t, rs)
+}
+
+func readResponse(t *testing.T, rs *http.Response) (int, http.Header, string) {
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs.StatusCode, rs.Header, string(bytes.TrimSpace(body))
+}
+
+var csrfTokenRX = regexp.MustCompile(`<input type="hidden" name="csrf_token" value="(.+?)">`)
+
+// csrfToken fetches a page with a form on it and returns the token that
+// posting the form needs.
+func (ts *testServer) csrfToken(t *testing.T, urlPath string) string {
+	_, _, body := ts.get(t, urlPath)
+	matches := csrfTokenRX.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatalf("no csrf token found on %s", urlPath)
+	}
+	return html.UnescapeString(matches[1])
+}
+
+// login signs in as one of the mock users: alice@example.com is user 1,
+// bob@example.com user 2.
+func (ts *testServer) login(t *testing.T, email string) {
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", "pa$$word")
+	form.Add("csrf_token", ts.csrfToken(t, "/user/login"))
+	code, header, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("login: got status %d (%s); want %d", code, header.Get("Location"), http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle counts failed attempts per key (a snippet id) and blocks further
+// attempts once max failures happened within window. It lives in memory,
+// so the counts reset when the server restarts.
+type throttle struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	failures map[int]*failures
+}
+
+type failures struct {
+	count int
+	since time.Time // First failure of the current window
+}
+
+func newThrottle(max int, window time.Duration) *throttle {
+	return &throttle{max: max, window: window, failures: map[int]*failures{}}
+}
+
+// Wait returns how long attempts on key stay blocked, 0 if they are allowed.
+func (t *throttle) Wait(key int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.failures[key]
+	if !ok {
+		return 0
+	}
+	left := t.window - time.Since(f.since)
+	if left <= 0 {
+		delete(t.failures, key)
+		return 0
+	}
+	if f.count < t.max {
+		return 0
+	}
+	return left
+}
+
+// Fail records a failed attempt on key.
+func (t *throttle) Fail(key int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	f, ok := t.failures[key]
+	if !ok || now.Sub(f.since) >= t.window {
+		t.prune(now)
+		f = &failures{since: now}
+		t.failures[key] = f
+	}
+	f.count++
+}
+
+// Reset forgets the failures on key after a successful attempt.
+func (t *throttle) Reset(key int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
+
+// prune drops keys whose window has passed, so the map only holds recent failures.
+func (t *throttle) prune(now time.Time) {
+	for key, f := range t.failures {
+		if now.Sub(f.since) >= t.window {
+			delete(t.failures, key)
+		}
+	}
+}
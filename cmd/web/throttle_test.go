@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	const window = 50 * time.Millisecond
+	tests := []struct {
+		name        string
+		fails       int           // Failures recorded on key 1
+		reset       bool          // Reset after them
+		sleep       time.Duration // Before asking Wait
+		wantBlocked bool
+	}{
+		{"No failures", 0, false, 0, false},
+		{"Below max", 2, false, 0, false},
+		{"At max", 3, false, 0, true},
+		{"Above max", 5, false, 0, true},
+		{"Reset after a success", 3, true, 0, false},
+		{"Window passed", 3, false, window + 10*time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := newThrottle(3, window)
+			for i := 0; i < tt.fails; i++ {
+				th.Fail(1)
+			}
+			if tt.reset {
+				th.Reset(1)
+			}
+			time.Sleep(tt.sleep)
+			wait := th.Wait(1)
+			if blocked := wait > 0; blocked != tt.wantBlocked {
+				t.Errorf("got wait %v; want blocked %t", wait, tt.wantBlocked)
+			}
+			if wait > window {
+				t.Errorf("got wait %v; want at most the %v window", wait, window)
+			}
+			// Keys are counted separately
+			if wait := th.Wait(2); wait != 0 {
+				t.Errorf("key 2: got wait %v; want 0", wait)
+			}
+		})
+	}
+}
+
+func TestThrottleNewWindow(t *testing.T) {
+	const window = 50 * time.Millisecond
+	th := newThrottle(2, window)
+	th.Fail(1)
+	time.Sleep(window + 10*time.Millisecond)
+	// The old failure has expired, so this one starts a new count
+	th.Fail(1)
+	if wait := th.Wait(1); wait != 0 {
+		t.Errorf("got wait %v after one failure in the new window; want 0", wait)
+	}
+	th.Fail(1)
+	if wait := th.Wait(1); wait == 0 {
+		t.Error("got no wait after two failures in the new window; want blocked")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/iam-vl/snbox/internal/models"
+)
+
+// viewCounter tallies snippet page reads in memory and writes them to the
+// database in one batch every interval, instead of one UPDATE per read.
+// Reads not flushed yet are lost if the process dies without a graceful
+// shutdown.
+type viewCounter struct {
+	mu       sync.Mutex
+	pending  map[int]int // Reads per snippet id since the last flush
+	snippets models.SnippetStore
+	interval time.Duration
+	errorLog *log.Logger
+}
+
+func newViewCounter(snippets models.SnippetStore, interval time.Duration, errorLog *log.Logger) *viewCounter {
+	return &viewCounter{pending: map[int]int{}, snippets: snippets, interval: interval, errorLog: errorLog}
+}
+
+// Add counts one read of a snippet.
+func (vc *viewCounter) Add(id int) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.pending[id]++
+}
+
+// Pending returns the reads of a snippet not flushed yet, to add to the stored count.
+func (vc *viewCounter) Pending(id int) int {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	return vc.pending[id]
+}
+
+// Run flushes every interval until ctx is cancelled. The caller does the last
+// Flush once the server has stopped handling requests.
+func (vc *viewCounter) Run(ctx context.Context) {
+	ticker := time.NewTicker(vc.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			vc.Flush()
+		}
+	}
+}
+
+// Flush writes the pending reads to the database. If that fails they are
+// put back, to be retried with the next flush.
+func (vc *viewCounter) Flush() {
+	vc.mu.Lock()
+	counts := vc.pending
+	vc.pending = map[int]int{}
+	vc.mu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+	if err := vc.snippets.AddViews(counts); err != nil {
+		vc.errorLog.Printf("view counter: %v", err)
+		vc.mu.Lock()
+		for id, n := range counts {
+			vc.pending[id] += n
+		}
+		vc.mu.Unlock()
+	}
+}
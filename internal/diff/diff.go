@@ -0,0 +1,286 @@
+// Package diff computes line-based differences between two texts and lays
+// them out as unified hunks or side-by-side rows for display.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Line is one line of the edit script. OldNum and NewNum are 1-based line
+// numbers in the old and new text, 0 when the line does not exist on that side.
+type Line struct {
+	Op     Op
+	Text   string
+	OldNum int
+	NewNum int
+}
+
+// Class is the CSS class used to mark the line in templates.
+func (l Line) Class() string {
+	switch l.Op {
+	case Insert:
+		return "added"
+	case Delete:
+		return "removed"
+	default:
+		return ""
+	}
+}
+
+// Prefix is the unified diff marker for the line.
+func (l Line) Prefix() string {
+	switch l.Op {
+	case Insert:
+		return "+"
+	case Delete:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// Lines returns the shortest edit script that turns a into b, line by line.
+func Lines(a, b string) []Line {
+	x, y := split(a), split(b)
+	script := myers(x, y)
+	oldNum, newNum := 0, 0
+	for i := range script {
+		switch script[i].Op {
+		case Equal:
+			oldNum++
+			newNum++
+			script[i].OldNum, script[i].NewNum = oldNum, newNum
+		case Delete:
+			oldNum++
+			script[i].OldNum = oldNum
+		case Insert:
+			newNum++
+			script[i].NewNum = newNum
+		}
+	}
+	return script
+}
+
+// Changed reports whether the script contains any insertion or deletion.
+func Changed(lines []Line) bool {
+	for _, l := range lines {
+		if l.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// myers is the O(ND) algorithm from Eugene Myers' "An O(ND) Difference
+// Algorithm and Its Variations": walk the edit graph diagonal by diagonal,
+// remembering the furthest point reached per diagonal k for each edit count d,
+// then backtrack through those snapshots to recover the script.
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] holds v[k] for k in [-d-1, d+1] as it was before round d.
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // move down: insertion
+			} else {
+				x = v[offset+k-1] + 1 // move right: deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return backtrack(a, b, trace)
+}
+
+func backtrack(a, b []string, trace [][]int) []Line {
+	var script []Line
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			script = append(script, Line{Op: Equal, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				script = append(script, Line{Op: Insert, Text: b[y-1]})
+			} else {
+				script = append(script, Line{Op: Delete, Text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	// Built back to front
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
+
+// Hunk is one block of a unified diff: the changed lines plus their context.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// Header renders the "@@ -a,b +c,d @@" line of the hunk.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Unified groups an edit script into hunks, keeping up to context unchanged
+// lines around every change. Changes closer than 2*context lines share a hunk.
+func Unified(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	i := 0
+	for i < len(lines) {
+		for i < len(lines) && lines[i].Op == Equal {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Op == Equal {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				end += context
+				if end > len(lines) {
+					end = len(lines)
+				}
+				break
+			}
+			end = run
+		}
+		hunks = append(hunks, newHunk(lines, start, end))
+		i = end
+	}
+	return hunks
+}
+
+func newHunk(lines []Line, start, end int) Hunk {
+	h := Hunk{Lines: lines[start:end]}
+	oldBefore, newBefore := 0, 0
+	for _, l := range lines[:start] {
+		if l.Op != Insert {
+			oldBefore++
+		}
+		if l.Op != Delete {
+			newBefore++
+		}
+	}
+	for _, l := range h.Lines {
+		if l.Op != Insert {
+			h.OldLines++
+		}
+		if l.Op != Delete {
+			h.NewLines++
+		}
+	}
+	// Like diff -u, an empty side points at the line before the hunk
+	h.OldStart, h.NewStart = oldBefore, newBefore
+	if h.OldLines > 0 {
+		h.OldStart++
+	}
+	if h.NewLines > 0 {
+		h.NewStart++
+	}
+	return h
+}
+
+// Row is one line of a side-by-side diff. Left or Right is nil
+// when that side has no line at this position.
+type Row struct {
+	Left  *Line
+	Right *Line
+}
+
+// SideBySide pairs the lines of an edit script into rows: unchanged lines sit
+// on both sides, and a block of deletions is lined up against the insertions
+// that follow it.
+func SideBySide(lines []Line) []Row {
+	var rows []Row
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			rows = append(rows, Row{Left: &lines[i], Right: &lines[i]})
+			i++
+			continue
+		}
+		var dels, ins []*Line
+		for i < len(lines) && lines[i].Op == Delete {
+			dels = append(dels, &lines[i])
+			i++
+		}
+		for i < len(lines) && lines[i].Op == Insert {
+			ins = append(ins, &lines[i])
+			i++
+		}
+		for j := 0; j < len(dels) || j < len(ins); j++ {
+			var row Row
+			if j < len(dels) {
+				row.Left = dels[j]
+			}
+			if j < len(ins) {
+				row.Right = ins[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
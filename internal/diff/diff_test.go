@@ -0,0 +1,215 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// script renders an edit script as diff -u style lines, e.g. "-b" or " a".
+func script(lines []Line) []string {
+	out := []string{}
+	for _, l := range lines {
+		out = append(out, l.Prefix()+l.Text)
+	}
+	return out
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{"Both empty", "", "", []string{}},
+		{"Identical", "a\nb\n", "a\nb\n", []string{" a", " b"}},
+		{"Pure insertion", "", "a\nb\n", []string{"+a", "+b"}},
+		{"Pure deletion", "a\nb\n", "", []string{"-a", "-b"}},
+		{"Line removed", "a\nb\nc", "a\nc", []string{" a", "-b", " c"}},
+		{"Line added", "a\nc", "a\nb\nc", []string{" a", "+b", " c"}},
+		{"Line replaced", "a\nb\nc", "a\nx\nc", []string{" a", "-b", "+x", " c"}},
+		{"CRLF against LF", "a\r\nb\r\n", "a\nb\n", []string{" a", " b"}},
+		{"Missing final newline", "a\nb", "a\nb\n", []string{" a", " b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := script(Lines(tt.a, tt.b))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLinesMyers checks that the script is a shortest one and that it really
+// turns a into b, with line numbers counting up on each side.
+func TestLinesMyers(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		wantEdits int
+	}{
+		{"Myers paper example", "A\nB\nC\nA\nB\nB\nA", "C\nB\nA\nB\nA\nC", 5},
+		{"Disjoint", "a\nb\nc", "x\ny", 5},
+		{"Repeated lines", "a\na\na\nb", "b\na\na\na", 2},
+		{"Swap halves", "1\n2\n3\n4\n5\n6", "4\n5\n6\n1\n2\n3", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := Lines(tt.a, tt.b)
+			var old, new []string
+			edits, oldNum, newNum := 0, 0, 0
+			for _, l := range lines {
+				if l.Op != Equal {
+					edits++
+				}
+				if l.Op != Insert {
+					oldNum++
+					old = append(old, l.Text)
+					if l.OldNum != oldNum {
+						t.Errorf("%q: got OldNum %d; want %d", l.Text, l.OldNum, oldNum)
+					}
+				}
+				if l.Op != Delete {
+					newNum++
+					new = append(new, l.Text)
+					if l.NewNum != newNum {
+						t.Errorf("%q: got NewNum %d; want %d", l.Text, l.NewNum, newNum)
+					}
+				}
+			}
+			if edits != tt.wantEdits {
+				t.Errorf("got %d edits; want %d", edits, tt.wantEdits)
+			}
+			if got := strings.Join(old, "\n"); got != tt.a {
+				t.Errorf("old side: got %q; want %q", got, tt.a)
+			}
+			if got := strings.Join(new, "\n"); got != tt.b {
+				t.Errorf("new side: got %q; want %q", got, tt.b)
+			}
+		})
+	}
+}
+
+// numbered returns the lines "1" to "n" with one of them replaced.
+func numbered(n int, replace int, with string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if i == replace {
+			b.WriteString(with + "\n")
+		} else {
+			fmt.Fprintf(&b, "%d\n", i)
+		}
+	}
+	return b.String()
+}
+
+// edges changes the first and the last line, with gap unchanged lines between them.
+func edges(gap int) (string, string) {
+	middle := strings.Repeat("=\n", gap)
+	return "x\n" + middle + "y\n", "X\n" + middle + "Y\n"
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    []string // Hunk headers
+	}{
+		{"No change", "a\nb\n", "a\nb\n", 3, nil},
+		{"Middle change", numbered(10, 5, "five"), numbered(10, 0, ""), 2, []string{"@@ -3,5 +3,5 @@"}},
+		{"Context cut at the start", numbered(10, 1, "one"), numbered(10, 0, ""), 3, []string{"@@ -1,4 +1,4 @@"}},
+		{"Context cut at the end", numbered(10, 10, "ten"), numbered(10, 0, ""), 3, []string{"@@ -7,4 +7,4 @@"}},
+		// An empty side starts at the line before the hunk, 0 for an empty text
+		{"Pure insertion into empty", "", "a\nb\n", 3, []string{"@@ -0,0 +1,2 @@"}},
+		{"Pure deletion to empty", "a\nb\n", "", 3, []string{"@@ -1,2 +0,0 @@"}},
+		{"Insertion at the top without context", "a\nb\n", "x\na\nb\n", 0, []string{"@@ -0,0 +1,1 @@"}},
+		{"Insertion in the middle without context", "a\nb\n", "a\nx\nb\n", 0, []string{"@@ -1,0 +2,1 @@"}},
+		{"Deletion in the middle without context", "a\nx\nb\n", "a\nb\n", 0, []string{"@@ -2,1 +1,0 @@"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, h := range Unified(Lines(tt.a, tt.b), tt.context) {
+				got = append(got, h.Header())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUnifiedMerge checks the boundary at which two changes share a hunk:
+// up to 2*context unchanged lines between them are shown in full.
+func TestUnifiedMerge(t *testing.T) {
+	const context = 2
+	tests := []struct {
+		name string
+		gap  int
+		want [][]string // Lines of each hunk
+	}{
+		{"Adjacent", 0, [][]string{{"-x", "-y", "+X", "+Y"}}},
+		{"Gap of 2*context", 2 * context, [][]string{
+			{"-x", "+X", " =", " =", " =", " =", "-y", "+Y"},
+		}},
+		{"Gap of 2*context+1", 2*context + 1, [][]string{
+			{"-x", "+X", " =", " ="},
+			{" =", " =", "-y", "+Y"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := edges(tt.gap)
+			hunks := Unified(Lines(a, b), context)
+			var got [][]string
+			for _, h := range hunks {
+				got = append(got, script(h.Lines))
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+	// The second hunk of the split case starts after the gap's first context lines
+	a, b := edges(2*context + 1)
+	hunks := Unified(Lines(a, b), context)
+	if got, want := hunks[1].Header(), "@@ -5,3 +5,3 @@"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string // "left|right", with "" for a missing side
+	}{
+		{"Empty", "", "", nil},
+		{"Unchanged lines on both sides", "a\nb", "a\nb", []string{" a| a", " b| b"}},
+		{"Replacement paired", "a\nb\nc", "a\nB\nc", []string{" a| a", "-b|+B", " c| c"}},
+		{"More insertions than deletions", "a\nb\nc", "a\nB\nC\nD\nc", []string{" a| a", "-b|+B", "|+C", "|+D", " c| c"}},
+		{"More deletions than insertions", "a\nb\nc\nd", "a\nB\nd", []string{" a| a", "-b|+B", "-c|", " d| d"}},
+		{"Pure insertion", "", "a\nb", []string{"|+a", "|+b"}},
+		{"Pure deletion", "a\nb", "", []string{"-a|", "-b|"}},
+	}
+	side := func(l *Line) string {
+		if l == nil {
+			return ""
+		}
+		return l.Prefix() + l.Text
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, row := range SideBySide(Lines(tt.a, tt.b)) {
+				got = append(got, side(row.Left)+"|"+side(row.Right))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
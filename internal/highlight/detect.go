@@ -0,0 +1,228 @@
+package highlight
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// MinConfidence is the score below which Detect gives up and returns "", 0.
+const MinConfidence = 0.3
+
+// Detect guesses the language of content and how sure it is, from 0 to 1.
+// It tries the strongest evidence first: a shebang, then file-type hints such
+// as editor modelines or <?php, then Go's own parser, then JSON, and finally
+// falls back to counting language-specific keywords.
+func Detect(content string) (string, float64) {
+	content = strings.TrimSpace(strings.ReplaceAll(content, "\r\n", "\n"))
+	if content == "" {
+		return "", 0
+	}
+	if lang := fromShebang(content); lang != "" {
+		return lang, 1
+	}
+	if lang := fromHints(content); lang != "" {
+		return lang, 0.9
+	}
+	if conf := goConfidence(content); conf > 0 {
+		return "go", conf
+	}
+	if (content[0] == '{' || content[0] == '[') && json.Valid([]byte(content)) {
+		return "json", 0.95
+	}
+	lang, conf := fromKeywords(content)
+	if conf < MinConfidence {
+		return "", 0
+	}
+	return lang, conf
+}
+
+// Language of a file name's extension, or of the whole name
+var extensions = map[string]string{
+	".sh": "bash", ".bash": "bash", ".zsh": "bash", ".c": "c", ".h": "c",
+	".cc": "cpp", ".cpp": "cpp", ".cxx": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".css": "css", ".diff": "diff", ".patch": "diff", "dockerfile": "docker",
+	".go": "go", ".html": "html", ".htm": "html", ".java": "java",
+	".js": "javascript", ".mjs": "javascript", ".json": "json",
+	".kt": "kotlin", ".kts": "kotlin", ".md": "markdown", ".php": "php",
+	".py": "python", ".rb": "ruby", ".rs": "rust", ".sql": "sql",
+	".toml": "toml", ".ts": "typescript", ".yaml": "yaml", ".yml": "yaml",
+}
+
+// DetectFile is Detect for a named file: a known extension (or a name such
+// as Dockerfile) settles it before the content is looked at.
+func DetectFile(name, content string) (string, float64) {
+	if lang, ok := extensions[strings.ToLower(path.Ext(name))]; ok {
+		return lang, 1
+	}
+	if lang, ok := extensions[strings.ToLower(name)]; ok {
+		return lang, 1
+	}
+	return Detect(content)
+}
+
+// Interpreters named on a #! line
+var shebangs = map[string]string{
+	"sh": "bash", "bash": "bash", "zsh": "bash", "dash": "bash",
+	"python": "python", "python2": "python", "python3": "python",
+	"node": "javascript", "deno": "typescript", "ts-node": "typescript",
+	"ruby": "ruby", "php": "php",
+}
+
+func fromShebang(content string) string {
+	first, _, _ := strings.Cut(content, "\n")
+	if !strings.HasPrefix(first, "#!") {
+		return ""
+	}
+	fields := strings.Fields(first[2:])
+	for i := len(fields) - 1; i >= 0; i-- {
+		// "/usr/bin/env python3" and "/bin/bash -e" both work
+		name := fields[i][strings.LastIndex(fields[i], "/")+1:]
+		if lang, ok := shebangs[name]; ok {
+			return lang
+		}
+	}
+	return ""
+}
+
+var (
+	// vim: set ft=python:  /  vim: syntax=go  /  -*- mode: ruby -*-
+	modeline = regexp.MustCompile(`(?:vim?:.*\b(?:ft|filetype|syntax)=|-\*-\s*mode:\s*)([A-Za-z0-9+#]+)`)
+	// The first lines of a unified diff
+	diffHeader = regexp.MustCompile(`(?m)^(?:--- \S.*\n\+\+\+ \S|diff --git |@@ -\d+(?:,\d+)? \+\d+(?:,\d+)? @@)`)
+	// A Dockerfile starts with FROM, optionally after ARG or comments
+	dockerFrom = regexp.MustCompile(`(?im)^FROM\s+\S+(\s+AS\s+\S+)?\s*$`)
+)
+
+// Modeline names that differ from our language names
+var modeAliases = map[string]string{
+	"sh": "bash", "zsh": "bash", "js": "javascript", "ts": "typescript",
+	"py": "python", "rb": "ruby", "yml": "yaml", "md": "markdown",
+	"c++": "cpp", "cs": "csharp", "dockerfile": "docker", "golang": "go", "rs": "rust",
+}
+
+func fromHints(content string) string {
+	if m := modeline.FindStringSubmatch(content); m != nil {
+		name := strings.ToLower(m[1])
+		if alias, ok := modeAliases[name]; ok {
+			name = alias
+		}
+		for _, l := range Languages {
+			if l.Name == name {
+				return name
+			}
+		}
+	}
+	lower := strings.ToLower(content)
+	switch {
+	case strings.HasPrefix(content, "<?php"):
+		return "php"
+	case strings.HasPrefix(lower, "<!doctype html") || strings.HasPrefix(lower, "<html"):
+		return "html"
+	case diffHeader.MatchString(content):
+		return "diff"
+	case dockerFrom.MatchString(content) && strings.Contains(content, "\nRUN "):
+		return "docker"
+	}
+	return ""
+}
+
+// goConfidence asks go/parser. A whole file with a package clause is certain;
+// declarations or statements that only parse once wrapped in a file or a
+// function are likely Go if they also use Go-only syntax.
+func goConfidence(content string) float64 {
+	fset := token.NewFileSet()
+	if strings.HasPrefix(content, "package ") {
+		if _, err := parser.ParseFile(fset, "", content, parser.SkipObjectResolution); err == nil {
+			return 1
+		}
+		return 0
+	}
+	if !goOnly.MatchString(content) {
+		return 0
+	}
+	if _, err := parser.ParseFile(fset, "", "package p\n"+content, parser.SkipObjectResolution); err == nil {
+		return 0.9
+	}
+	if _, err := parser.ParseFile(fset, "", "package p\nfunc _() {\n"+content+"\n}", parser.SkipObjectResolution); err == nil {
+		return 0.8
+	}
+	return 0
+}
+
+// Syntax hardly any other language shares
+var goOnly = regexp.MustCompile(`:=|\bfunc\b|\bchan\b|\bdefer\b|\bgo func\b|\bfmt\.|\bif err != nil\b`)
+
+// keyword is one piece of evidence for a language, worth weight points per match.
+type keyword struct {
+	rx     *regexp.Regexp
+	weight int
+}
+
+func kw(weight int, pattern string) keyword {
+	return keyword{regexp.MustCompile(pattern), weight}
+}
+
+// Keywords and idioms per language. Each pattern counts at most a few times
+// (see fromKeywords), so one repeated word cannot outvote everything else.
+var keywords = map[string][]keyword{
+	"python": {kw(3, `(?m)^\s*def \w+\(.*\):\s*$`), kw(2, `(?m)^\s*(?:from \w[\w.]* )?import \w`), kw(2, `\bself\.`),
+		kw(2, `(?m)^\s*elif\b`), kw(1, `\bNone\b`), kw(1, `\bprint\(`), kw(2, `(?m)^\s*class \w+(?:\(.*\))?:\s*$`), kw(2, `__\w+__`)},
+	"javascript": {kw(2, `\bfunction\b`), kw(2, `\bconst \w+ =`), kw(1, `\blet \w+`), kw(2, `=>`), kw(3, `\bconsole\.log\(`),
+		kw(2, `\brequire\(['"]`), kw(1, `===`), kw(2, `\bdocument\.`), kw(1, `\bexport default\b`)},
+	"typescript": {kw(3, `\binterface \w+ \{`), kw(3, `:\s*(?:string|number|boolean)\b`), kw(3, `\bexport type\b`),
+		kw(1, `\bconst \w+ =`), kw(1, `=>`), kw(2, `\bimport .* from ['"]`)},
+	"java": {kw(3, `\bpublic (?:final )?class\b`), kw(3, `\bSystem\.out\.print`), kw(2, `\bprivate \w+ \w+;`),
+		kw(2, `\bpublic static void main\(String`), kw(2, `(?m)^import java\.`), kw(1, `@Override`)},
+	"c": {kw(3, `(?m)^#include <\w+\.h>`), kw(2, `\bprintf\(`), kw(2, `\bint main\(`), kw(1, `\bmalloc\(`), kw(1, `\bstruct \w+ \{`)},
+	"cpp": {kw(3, `\bstd::`), kw(3, `(?m)^#include <(?:iostream|vector|string|map)>`), kw(2, `\bcout\s*<<`),
+		kw(2, `\btemplate\s*<`), kw(1, `\bnamespace \w+`), kw(1, `::`)},
+	"csharp": {kw(3, `(?m)^using System`), kw(3, `\bConsole\.Write`), kw(2, `\bnamespace \w+`), kw(1, `\bpublic static void Main\b`),
+		kw(2, `\{ get; set; \}`), kw(1, `\bvar \w+ = new\b`)},
+	"rust": {kw(3, `\bfn \w+\(`), kw(3, `\blet mut\b`), kw(2, `\bimpl\b`), kw(2, `\bprintln!\(`), kw(1, `->`),
+		kw(2, `\buse \w+::`), kw(1, `::new\(`), kw(2, `&mut\b`)},
+	"ruby": {kw(2, `(?m)^\s*def \w+[?!]?(?:\(.*\))?\s*$`), kw(2, `(?m)^\s*end\s*$`), kw(2, `\bputs\b`), kw(2, `\bdo \|\w+\|`),
+		kw(2, `(?m)^require ['"]`), kw(1, `@\w+`), kw(1, `\battr_accessor\b`)},
+	"php": {kw(3, `\$\w+\s*=`), kw(2, `\becho\b`), kw(2, `->\w+\(`), kw(2, `\bfunction \w+\(\$`)},
+	"sql": {kw(3, `(?i)\bselect\b[\s\S]+\bfrom\b`), kw(3, `(?i)\binsert into\b`), kw(3, `(?i)\bcreate table\b`),
+		kw(2, `(?i)\bwhere\b`), kw(2, `(?i)\b(?:inner|left|right) join\b`), kw(2, `(?i)\bupdate \w+ set\b`), kw(1, `(?i)\border by\b`)},
+	"bash": {kw(2, `(?m)^\s*echo\b`), kw(3, `(?m)^\s*fi\s*$`), kw(2, `(?m);\s*then\s*$`), kw(2, `\$\(`), kw(1, `\$\{?\w+\}?`),
+		kw(2, `(?m)^\s*export \w+=`), kw(2, `(?m)^\s*(?:sudo |apt(?:-get)? |cd |ls |grep |curl )`), kw(2, `\besac\b`)},
+	"css": {kw(3, `(?m)^[.#]?[\w-]+(?:[ ,>.:#][\w-]+)*\s*\{\s*$`), kw(2, `(?m)^\s*[\w-]+:\s*[^;]+;\s*$`), kw(2, `@media\b`),
+		kw(1, `\b\d+px\b`), kw(1, `#[0-9A-Fa-f]{6}\b`)},
+	"html":     {kw(3, `</\w+>`), kw(2, `<(?:div|span|p|a|ul|li|body|head|script)\b`), kw(1, `\bclass="`)},
+	"markdown": {kw(2, "(?m)^#{1,6} \\S"), kw(3, "(?m)^```"), kw(2, `\[[^\]]+\]\([^)]+\)`), kw(1, `\*\*\w`), kw(1, `(?m)^[-*] \S`)},
+	"yaml":     {kw(2, `(?m)^[\w-]+:\s*$`), kw(2, `(?m)^\s+[\w-]+: \S`), kw(2, `(?m)^\s*- [\w-]+: `), kw(2, `(?m)^---\s*$`)},
+	"toml":     {kw(3, `(?m)^\[[\w.-]+\]\s*$`), kw(2, `(?m)^[\w-]+ = (?:"|\d|true|false|\[)`)},
+	"kotlin":   {kw(3, `\bfun \w+\(`), kw(2, `\bval \w+`), kw(1, `\bvar \w+`), kw(2, `\bprintln\(`), kw(2, `\bdata class\b`)},
+	"go":       {kw(2, `\bfunc\b`), kw(3, `:=`), kw(2, `\bfmt\.`), kw(2, `\bif err != nil\b`), kw(2, `(?m)^package \w+`)},
+}
+
+// fromKeywords scores every language by its weighted keyword matches. The
+// confidence grows with the amount of evidence and with the lead of the best
+// language over the runner-up.
+func fromKeywords(content string) (string, float64) {
+	best, second := 0, 0
+	lang := ""
+	for _, l := range Languages {
+		score := 0
+		for _, k := range keywords[l.Name] {
+			score += k.weight * min(len(k.rx.FindAllStringIndex(content, 3)), 3)
+		}
+		switch {
+		case score > best:
+			lang, best, second = l.Name, score, best
+		case score > second:
+			second = score
+		}
+	}
+	if best == 0 {
+		return "", 0
+	}
+	lead := float64(best-second) / float64(best)
+	evidence := min(float64(best)/12, 1)
+	return lang, lead * evidence
+}
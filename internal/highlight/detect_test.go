@@ -0,0 +1,79 @@
+package highlight
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantLang string
+		minConf  float64 // The confidence must fall in [minConf, maxConf]
+		maxConf  float64
+	}{
+		{"Empty", "", "", 0, 0},
+		{"Blank lines", "\n  \r\n\t", "", 0, 0},
+		{"Prose", "An old silent pond\nA frog jumps into the pond\nSplash! Silence again.", "", 0, 0},
+		// A shebang settles it
+		{"Shebang through env", "#!/usr/bin/env python3\nprint('hi')", "python", 1, 1},
+		{"Shebang with flags", "#!/bin/bash -e\nls", "bash", 1, 1},
+		{"Shebang with CRLF", "#!/bin/sh\r\necho hi\r\n", "bash", 1, 1},
+		{"Shebang beats hints", "#!/usr/bin/env node\n// vim: ft=python\n", "javascript", 1, 1},
+		// File-type hints
+		{"Vim modeline", "# vim: set ft=python:\nx = 1", "python", 0.9, 0.9},
+		{"Vim modeline alias", "// vim: syntax=golang\nx", "go", 0.9, 0.9},
+		{"Emacs mode line", "# -*- mode: ruby -*-\nx = 1", "ruby", 0.9, 0.9},
+		{"PHP opening tag", "<?php\nfoo();", "php", 0.9, 0.9},
+		{"HTML doctype", "<!DOCTYPE html>\n<title>x</title>", "html", 0.9, 0.9},
+		{"Unified diff", "--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n-x\n+y", "diff", 0.9, 0.9},
+		{"Dockerfile", "FROM golang:1.22 AS build\nRUN go build ./...\n", "docker", 0.9, 0.9},
+		// Go's own parser
+		{"Go file", "package main\n\nfunc main() {}\n", "go", 1, 1},
+		{"Go declaration", "func add(a, b int) int {\n\treturn a + b\n}", "go", 0.9, 0.9},
+		{"Go statements", "x := 1\nfmt.Println(x)", "go", 0.8, 0.8},
+		// JSON
+		{"JSON object", `{"name": "snbox", "tags": ["go", "web"]}`, "json", 0.95, 0.95},
+		{"JSON array", `[1, 2, 3]`, "json", 0.95, 0.95},
+		// Keyword heuristics
+		{"SQL query", "SELECT id, title FROM snippets\nWHERE expires > NOW()\nORDER BY id DESC", "sql", MinConfidence, 1},
+		{"Python", "import os\n\nclass Walker:\n    def walk(self, root):\n        if self.seen is None:\n            print(os.listdir(root))\n", "python", MinConfidence, 1},
+		{"CSS", ".pond {\n    color: #336699;\n    margin: 10px;\n}\n", "css", MinConfidence, 1},
+		{"Too little evidence", "x = None", "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, conf := Detect(tt.content)
+			if lang != tt.wantLang {
+				t.Errorf("got language %q; want %q", lang, tt.wantLang)
+			}
+			if conf < tt.minConf || conf > tt.maxConf {
+				t.Errorf("got confidence %v; want between %v and %v", conf, tt.minConf, tt.maxConf)
+			}
+		})
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		wantLang string
+		wantConf float64
+	}{
+		{"Extension beats content", "main.go", "#!/usr/bin/env python3\n", "go", 1},
+		{"Extension in capitals", "SETUP.PY", "", "python", 1},
+		{"Whole name", "Dockerfile", "", "docker", 1},
+		{"Path", "cmd/web/main.go", "", "go", 1},
+		{"Unknown extension falls back to content", "config.cfg", `{"debug": true}`, "json", 0.95},
+		{"No extension falls back to content", "run", "#!/bin/sh\necho hi", "bash", 1},
+		{"Nothing to go on", "notes.txt", "An old silent pond", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, conf := DetectFile(tt.fileName, tt.content)
+			if lang != tt.wantLang || conf != tt.wantConf {
+				t.Errorf("got %q, %v; want %q, %v", lang, conf, tt.wantLang, tt.wantConf)
+			}
+		})
+	}
+}
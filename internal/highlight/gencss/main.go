@@ -0,0 +1,21 @@
+// Command gencss writes the stylesheet for highlight.HTML's CSS classes.
+// Run it through go generate ./internal/highlight after changing highlight.Style.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/iam-vl/snbox/internal/highlight"
+)
+
+func main() {
+	f, err := os.Create("../../ui/static/css/chroma.css")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if err = highlight.WriteCSS(f); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,179 @@
+// Package highlight renders snippet content as syntax highlighted HTML with
+// line numbers. The markup only uses CSS classes (see ui/static/css/chroma.css),
+// never inline styles, so it works under the Content-Security-Policy.
+package highlight
+
+//go:generate go run ./gencss
+
+import (
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Language is one entry of the create form's language selector.
+type Language struct {
+	Name  string // Chroma lexer alias, stored on the snippet
+	Label string
+}
+
+// Languages offered on the create form, in display order.
+// "" (no language) renders as plain text.
+var Languages = []Language{
+	{"bash", "Bash"},
+	{"c", "C"},
+	{"cpp", "C++"},
+	{"csharp", "C#"},
+	{"css", "CSS"},
+	{"diff", "Diff"},
+	{"docker", "Dockerfile"},
+	{"go", "Go"},
+	{"html", "HTML"},
+	{"java", "Java"},
+	{"javascript", "JavaScript"},
+	{"json", "JSON"},
+	{"kotlin", "Kotlin"},
+	{"markdown", "Markdown"},
+	{"php", "PHP"},
+	{"python", "Python"},
+	{"ruby", "Ruby"},
+	{"rust", "Rust"},
+	{"sql", "SQL"},
+	{"toml", "TOML"},
+	{"typescript", "TypeScript"},
+	{"yaml", "YAML"},
+}
+
+// Names lists the Name of every supported language, for form validation.
+func Names() []string {
+	names := make([]string, len(Languages))
+	for i, l := range Languages {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// Label returns the display name of a language, "Plain text" for "" or unknown names.
+func Label(name string) string {
+	for _, l := range Languages {
+		if l.Name == name {
+			return l.Label
+		}
+	}
+	return "Plain text"
+}
+
+// Style is the chroma style the stylesheet is generated from.
+const Style = "github"
+
+// newFormatter links line numbers to themselves: with prefix "L" line 10 is #L10.
+func newFormatter(prefix string, options ...html.Option) *html.Formatter {
+	return html.New(append([]html.Option{
+		html.WithClasses(true),
+		html.WithLineNumbers(true),
+		html.WithLinkableLineNumbers(true, prefix),
+		html.TabWidth(4),
+	}, options...)...)
+}
+
+var formatter = newFormatter("L")
+
+// HTML highlights code as the named language. Chroma escapes every token,
+// so the result is safe to hand to html/template as-is.
+func HTML(code, language string) (template.HTML, error) {
+	return highlight(formatter, code, language)
+}
+
+// HTMLPrefixed is HTML with line anchors named prefix+number, so that
+// several files on one page do not share ids.
+func HTMLPrefixed(code, language, prefix string) (template.HTML, error) {
+	return highlight(newFormatter(prefix), code, language)
+}
+
+func tokenise(code, language string) (chroma.Iterator, error) {
+	lexer := lexers.Get(language)
+	if language == "" || lexer == nil {
+		lexer = lexers.Fallback
+	}
+	return chroma.Coalesce(lexer).Tokenise(nil, code)
+}
+
+func highlight(formatter *html.Formatter, code, language string) (template.HTML, error) {
+	iterator, err := tokenise(code, language)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err = formatter.Format(&b, styles.Get(Style), iterator); err != nil {
+		return "", err
+	}
+	return template.HTML(b.String()), nil
+}
+
+// HTMLSplit is HTMLPrefixed cut into len(after)+1 pieces, the first ending
+// with line after[0] and so on, so that something can be shown between them.
+// after must be ascending; a piece may be empty. The whole code is tokenised
+// at once, so a comment or string spanning a cut still highlights correctly,
+// and line numbers carry on from piece to piece. Lines within the marked
+// ranges get the "hl" class.
+func HTMLSplit(code, language, prefix string, after []int, marked [][2]int) ([]template.HTML, error) {
+	iterator, err := tokenise(code, language)
+	if err != nil {
+		return nil, err
+	}
+	lines := chroma.SplitTokensIntoLines(iterator.Tokens())
+	marked = mergeRanges(marked)
+	pieces := make([]template.HTML, 0, len(after)+1)
+	start := 0
+	for _, end := range append(after, len(lines)) {
+		end = min(max(end, start), len(lines))
+		if end == start {
+			pieces = append(pieces, "")
+			continue
+		}
+		var tokens []chroma.Token
+		for _, line := range lines[start:end] {
+			tokens = append(tokens, line...)
+		}
+		f := newFormatter(prefix, html.BaseLineNumber(start+1), html.HighlightLines(marked))
+		var b strings.Builder
+		if err = f.Format(&b, styles.Get(Style), chroma.Literator(tokens...)); err != nil {
+			return nil, err
+		}
+		pieces = append(pieces, template.HTML(b.String()))
+		start = end
+	}
+	return pieces, nil
+}
+
+// mergeRanges sorts line ranges and joins overlapping ones:
+// chroma expects them ascending and disjoint.
+func mergeRanges(ranges [][2]int) [][2]int {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b [2]int) int { return a[0] - b[0] })
+	merged := [][2]int{}
+	for _, r := range sorted {
+		if n := len(merged); n > 0 && r[0] <= merged[n-1][1]+1 {
+			merged[n-1][1] = max(merged[n-1][1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Lines counts the lines of code the way HTML numbers them.
+func Lines(code string) int {
+	return len(strings.Split(strings.TrimSuffix(code, "\n"), "\n"))
+}
+
+// WriteCSS writes the stylesheet for the classes HTML uses.
+func WriteCSS(w io.Writer) error {
+	return formatter.WriteCSS(w, styles.Get(Style))
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+)
+
+// MaxCollectionNameLength is enforced on the collection form.
+const MaxCollectionNameLength = 100
+
+// Collection is a user's named, ordered list of snippets.
+type Collection struct {
+	ID        int
+	OwnerID   int
+	OwnerName string
+	Name      string
+	// Visibility is that of the collection itself, not of its snippets.
+	// ShareToken is only set for Unlisted collections.
+	Visibility Visibility
+	ShareToken string
+	Created    time.Time
+	Size       int        // Entries: all of them in Owned, the visible ones in Get
+	Snippets   []*Snippet // Entries in order, only set by Get
+}
+
+// CollectionStore describes the collection operations the web app depends on.
+type CollectionStore interface {
+	Insert(ownerID int, name string, visibility Visibility) (int, error)
+	Get(id int, viewerID int) (*Collection, error)
+	GetShared(token string, viewerID int) (*Collection, error)
+	Owned(userID int) ([]*Collection, error)
+	Add(id int, snippetID int) error
+	Remove(id int, snippetID int) error
+	Move(id int, snippetID int, position int) error
+}
+
+type CollectionModel struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// Insert creates an empty collection. Unlisted ones get a fresh share token.
+func (m *CollectionModel) Insert(ownerID int, name string, visibility Visibility) (int, error) {
+	var token sql.NullString
+	if visibility == Unlisted {
+		t, err := newShareToken()
+		if err != nil {
+			return 0, err
+		}
+		token = sql.NullString{String: t, Valid: true}
+	}
+	stmt := `INSERT INTO collections (owner_id, name, visibility, share_token, created) VALUES (?, ?, ?, ?, ?)`
+	return m.Dialect.insert(m.DB, stmt, ownerID, name, visibility, token, now())
+}
+
+// Get returns a collection with the entries viewerID may see: live snippets
+// that are public or belong to the viewer. Whether the viewer may see the
+// collection itself is up to the caller.
+func (m *CollectionModel) Get(id int, viewerID int) (*Collection, error) {
+	query := `SELECT c.id, c.owner_id, u.name, c.name, c.visibility, COALESCE(c.share_token, ''), c.created
+	FROM collections c JOIN users u ON u.id = c.owner_id WHERE c.id = ?`
+	c := &Collection{}
+	err := m.DB.QueryRow(m.Dialect.rebind(query), id).Scan(&c.ID, &c.OwnerID, &c.OwnerName, &c.Name, &c.Visibility, &c.ShareToken, &c.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	c.Snippets, err = m.entries(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	c.Size = len(c.Snippets)
+	return c, nil
+}
+
+// GetShared returns the unlisted collection behind a share token.
+func (m *CollectionModel) GetShared(token string, viewerID int) (*Collection, error) {
+	query := `SELECT id FROM collections WHERE share_token = ? AND visibility = 'unlisted'`
+	var id int
+	err := m.DB.QueryRow(m.Dialect.rebind(query), token).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return m.Get(id, viewerID)
+}
+
+func (m *CollectionModel) entries(id int, viewerID int) ([]*Snippet, error) {
+	query := `SELECT s.id, s.title, s.created, s.expires, COALESCE(s.owner_id, 0), s.visibility, s.view_count
+	FROM collection_snippets cs JOIN snippets s ON s.id = cs.snippet_id
+	WHERE cs.collection_id = ? AND s.expires > ? AND s.deleted IS NULL AND (s.visibility = 'public' OR s.owner_id = ?)
+	ORDER BY cs.position, s.id`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), id, now(), viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{}
+		if err = rows.Scan(&s.ID, &s.Title, &s.Created, &s.Expires, &s.OwnerID, &s.Visibility, &s.Views); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// Owned lists the user's collections by name, with the number of entries in each.
+func (m *CollectionModel) Owned(userID int) ([]*Collection, error) {
+	query := `SELECT c.id, c.name, c.visibility, COALESCE(c.share_token, ''), c.created,
+	(SELECT COUNT(*) FROM collection_snippets cs WHERE cs.collection_id = c.id)
+	FROM collections c WHERE c.owner_id = ? ORDER BY c.name, c.id`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	collections := []*Collection{}
+	for rows.Next() {
+		c := &Collection{OwnerID: userID}
+		if err = rows.Scan(&c.ID, &c.Name, &c.Visibility, &c.ShareToken, &c.Created, &c.Size); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// Add appends a snippet to the end of a collection. Adding it again changes
+// nothing: the collection_snippets primary key turns it into a duplicate.
+func (m *CollectionModel) Add(id int, snippetID int) error {
+	stmt := `INSERT INTO collection_snippets (collection_id, snippet_id, position)
+	SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM collection_snippets WHERE collection_id = ?`
+	_, err := m.DB.Exec(m.Dialect.rebind(stmt), id, snippetID, id)
+	if err != nil && !m.Dialect.isDuplicate(err) {
+		return err
+	}
+	return nil
+}
+
+// Remove takes a snippet out of a collection. It returns ErrNoRecord if it was not in it.
+func (m *CollectionModel) Remove(id int, snippetID int) error {
+	stmt := `DELETE FROM collection_snippets WHERE collection_id = ? AND snippet_id = ?`
+	result, err := m.DB.Exec(m.Dialect.rebind(stmt), id, snippetID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Move puts a snippet at position (1 for the top, past the end for the
+// bottom) of the list as the owner sees it, and renumbers the whole
+// collection 1, 2, 3... so the gaps left by Remove close up. Entries the
+// owner can no longer see (expired or trashed snippets) are moved to the end.
+// It returns ErrNoRecord if the snippet is not in the collection.
+func (m *CollectionModel) Move(id int, snippetID int, position int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT cs.snippet_id FROM collection_snippets cs
+	JOIN collections c ON c.id = cs.collection_id JOIN snippets s ON s.id = cs.snippet_id
+	WHERE cs.collection_id = ?
+	ORDER BY CASE WHEN s.expires > ? AND s.deleted IS NULL AND (s.visibility = 'public' OR s.owner_id = c.owner_id) THEN 0 ELSE 1 END,
+	cs.position, cs.snippet_id`
+	rows, err := tx.Query(m.Dialect.rebind(query), id, now())
+	if err != nil {
+		return err
+	}
+	var order []int
+	for rows.Next() {
+		var sid int
+		if err = rows.Scan(&sid); err != nil {
+			rows.Close()
+			return err
+		}
+		order = append(order, sid)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	at := slices.Index(order, snippetID)
+	if at < 0 {
+		return ErrNoRecord
+	}
+	order = slices.Delete(order, at, at+1)
+	position = min(max(position, 1), len(order)+1)
+	order = slices.Insert(order, position-1, snippetID)
+
+	stmt := `UPDATE collection_snippets SET position = ? WHERE collection_id = ? AND snippet_id = ?`
+	for i, sid := range order {
+		if _, err = tx.Exec(m.Dialect.rebind(stmt), i+1, id, sid); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,236 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Limits enforced on the comment form
+const (
+	MaxCommentLength = 2000
+	// Replies deeper than this are still stored, but indented no further
+	MaxCommentDepth = 5
+)
+
+// Comment is one entry of a snippet's discussion.
+type Comment struct {
+	ID         int
+	SnippetID  int
+	ParentID   int // 0 for top-level comments
+	AuthorID   int // 0 once the author's account is gone
+	AuthorName string
+	Body       string // "" once deleted
+	Created    time.Time
+	Edited     time.Time // Zero unless edited
+	Deleted    bool
+	Depth      int // Indent level set by Thread: 0 for top-level comments, at most MaxCommentDepth
+	// Review comments are about lines LineStart to LineEnd of the main file
+	// as it was at Revision. All three are 0 for general comments and replies.
+	Revision  int
+	LineStart int
+	LineEnd   int
+}
+
+// IsReview reports whether the comment is attached to a line range.
+func (c *Comment) IsReview() bool {
+	return c.LineStart > 0
+}
+
+// Anchor is the fragment that selects the comment's lines, e.g. "L10-L14".
+// A single line is "L10-L10": "L10" is already the id of the line itself.
+func (c *Comment) Anchor() string {
+	return fmt.Sprintf("L%d-L%d", c.LineStart, c.LineEnd)
+}
+
+// NewComment is what Insert needs to add a comment.
+type NewComment struct {
+	SnippetID int
+	ParentID  int // Comment replied to, 0 for a new thread
+	AuthorID  int
+	Body      string
+	// Line range of a review comment, 0 otherwise. Replies have none of
+	// their own: they belong to their parent's lines.
+	Revision  int
+	LineStart int
+	LineEnd   int
+}
+
+// CommentStore describes the comment operations the web app depends on.
+type CommentStore interface {
+	Insert(n NewComment) (int, error)
+	Get(id int) (*Comment, error)
+	Thread(snippetID int) ([]*Comment, error)
+	Update(id int, body string) error
+	Delete(id int) error
+}
+
+type CommentModel struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// Insert adds a comment, or a reply when ParentID is set. It returns
+// ErrNoRecord if the parent is not a comment on the same snippet.
+func (m *CommentModel) Insert(n NewComment) (int, error) {
+	if n.ParentID != 0 {
+		var count int
+		query := `SELECT COUNT(*) FROM comments WHERE id = ? AND snippet_id = ?`
+		if err := m.DB.QueryRow(m.Dialect.rebind(query), n.ParentID, n.SnippetID).Scan(&count); err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return 0, ErrNoRecord
+		}
+		n.Revision, n.LineStart, n.LineEnd = 0, 0, 0
+	}
+	stmt := `INSERT INTO comments (snippet_id, parent_id, author_id, body, created, revision, line_start, line_end)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	return m.Dialect.insert(m.DB, stmt, n.SnippetID, nullID(n.ParentID), nullID(n.AuthorID), n.Body, now(),
+		nullID(n.Revision), nullID(n.LineStart), nullID(n.LineEnd))
+}
+
+const commentColumns = `c.id, c.snippet_id, COALESCE(c.parent_id, 0), COALESCE(c.author_id, 0), COALESCE(u.name, ''),
+	c.body, c.created, c.edited, c.deleted IS NOT NULL,
+	COALESCE(c.revision, 0), COALESCE(c.line_start, 0), COALESCE(c.line_end, 0)
+	FROM comments c LEFT JOIN users u ON u.id = c.author_id`
+
+func scanComment(row interface{ Scan(...any) error }) (*Comment, error) {
+	c := &Comment{}
+	var edited sql.NullTime
+	err := row.Scan(&c.ID, &c.SnippetID, &c.ParentID, &c.AuthorID, &c.AuthorName, &c.Body, &c.Created, &edited, &c.Deleted,
+		&c.Revision, &c.LineStart, &c.LineEnd)
+	if err != nil {
+		return nil, err
+	}
+	c.Edited = edited.Time
+	return c, nil
+}
+
+// Get returns a comment that has not been deleted.
+func (m *CommentModel) Get(id int) (*Comment, error) {
+	query := `SELECT ` + commentColumns + ` WHERE c.id = ? AND c.deleted IS NULL`
+	c, err := scanComment(m.DB.QueryRow(m.Dialect.rebind(query), id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Thread returns a snippet's discussion flattened in reading order: every
+// comment is followed by its replies, oldest first, with Depth set for
+// indenting. Deleted comments only stay as placeholders for their replies.
+func (m *CommentModel) Thread(snippetID int) ([]*Comment, error) {
+	query := `SELECT ` + commentColumns + ` WHERE c.snippet_id = ? ORDER BY c.created, c.id`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	replies := map[int][]*Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		replies[c.ParentID] = append(replies[c.ParentID], c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	thread := []*Comment{}
+	var walk func(parentID, depth int) bool
+	walk = func(parentID, depth int) bool {
+		shown := false
+		for _, c := range replies[parentID] {
+			c.Depth = min(depth, MaxCommentDepth)
+			at := len(thread)
+			thread = append(thread, c)
+			if !walk(c.ID, depth+1) && c.Deleted {
+				thread = thread[:at] // Nothing below it worth keeping
+				continue
+			}
+			shown = true
+		}
+		return shown
+	}
+	walk(0, 0)
+	return thread, nil
+}
+
+// Update replaces the body of a comment and marks it as edited.
+func (m *CommentModel) Update(id int, body string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Check the comment is there rather than counting affected rows: MySQL
+	// reports 0 for an UPDATE that leaves the row as it was.
+	var n int
+	query := `SELECT id FROM comments WHERE id = ? AND deleted IS NULL` + m.Dialect.forUpdate()
+	if err = tx.QueryRow(m.Dialect.rebind(query), id).Scan(&n); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+	stmt := `UPDATE comments SET body = ?, edited = ? WHERE id = ?`
+	if _, err = tx.Exec(m.Dialect.rebind(stmt), body, now(), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete blanks a comment. The row stays so that replies keep their place.
+func (m *CommentModel) Delete(id int) error {
+	stmt := `UPDATE comments SET body = '', deleted = ? WHERE id = ? AND deleted IS NULL`
+	result, err := m.DB.Exec(m.Dialect.rebind(stmt), now(), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// LockComments closes (or reopens) a snippet's discussion to new comments and edits.
+// Locking or unlocking it again is not an error.
+func (m *SnippetModel) LockComments(id int, locked bool) error {
+	var at sql.NullTime
+	if locked {
+		at = sql.NullTime{Time: now(), Valid: true}
+	}
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// As in CommentModel.Update, existence is checked explicitly: an already open thread
+	// left open is 0 affected rows on MySQL.
+	var n int
+	query := `SELECT id FROM snippets WHERE id = ? AND expires > ? AND deleted IS NULL` + m.Dialect.forUpdate()
+	if err = tx.QueryRow(m.Dialect.rebind(query), id, now()).Scan(&n); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+	stmt := `UPDATE snippets SET comments_locked = ? WHERE id = ?`
+	if _, err = tx.Exec(m.Dialect.rebind(stmt), at, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
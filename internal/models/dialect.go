@@ -0,0 +1,153 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect tells a model which SQL flavour the database behind its *sql.DB speaks.
+// The zero value is MySQL, so models built as &SnippetModel{DB: db} keep working.
+type Dialect int
+
+const (
+	MySQL Dialect = iota
+	SQLite
+	Postgres
+)
+
+// ParseDialect maps a -db-driver flag value to a Dialect.
+func ParseDialect(name string) (Dialect, error) {
+	switch name {
+	case "mysql":
+		return MySQL, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	case "postgres", "postgresql", "pgx":
+		return Postgres, nil
+	default:
+		return 0, fmt.Errorf("models: unsupported database driver %q", name)
+	}
+}
+
+func (d Dialect) String() string {
+	switch d {
+	case SQLite:
+		return "sqlite"
+	case Postgres:
+		return "postgres"
+	default:
+		return "mysql"
+	}
+}
+
+// DriverName returns the database/sql driver name to pass to sql.Open.
+func (d Dialect) DriverName() string {
+	if d == Postgres {
+		return "pgx"
+	}
+	return d.String()
+}
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx.
+type dbtx interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// rebind rewrites the ? placeholders used throughout the models
+// into the $1, $2... form Postgres expects.
+func (d Dialect) rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// insert runs an INSERT statement and returns the id of the new row.
+// Postgres has no LastInsertId, so the id comes back through RETURNING instead.
+func (d Dialect) insert(db dbtx, query string, args ...any) (int, error) {
+	if d == Postgres {
+		var id int
+		err := db.QueryRow(d.rebind(query)+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	// get the new record's id
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// forUpdate is appended to a SELECT that reads a row a transaction is about
+// to change, so that concurrent writers queue up behind the row lock.
+// SQLite has no row locks: there a write transaction locks the whole
+// database, from BEGIN when the DSN sets _txlock=immediate.
+func (d Dialect) forUpdate() string {
+	if d == SQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// isDuplicate reports whether err is a unique (or primary key) constraint violation.
+func (d Dialect) isDuplicate(err error) bool {
+	switch d {
+	case SQLite:
+		var sqliteError *sqlite.Error
+		if errors.As(err, &sqliteError) {
+			code := sqliteError.Code()
+			return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+		}
+	case Postgres:
+		var pgError *pgconn.PgError
+		if errors.As(err, &pgError) {
+			// SQLSTATE 23505 is unique_violation
+			return pgError.Code == "23505"
+		}
+	default:
+		var mySqlError *mysql.MySQLError
+		// Using errors.As to check wether the error has the time *mysql.MySQLError. If so, assigning the error
+		if errors.As(err, &mySqlError) {
+			return mySqlError.Number == 1062
+		}
+	}
+	return false
+}
+
+// nullID stores a user id of 0 (nobody logged in) as NULL, to keep foreign keys happy.
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id > 0}
+}
+
+// now returns the current UTC time truncated to whole seconds.
+// Timestamps are computed here rather than with UTC_TIMESTAMP() and friends,
+// so that every dialect stores and compares them the same way.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
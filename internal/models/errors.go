@@ -9,4 +9,10 @@ var (
 	ErrInvalidCreds = errors.New("models: invalid creds")
 	// Existing email error
 	ErrDuplicateEmail = errors.New("models: duplicate email")
+	// Malformed ?cursor= value for a paginated listing
+	ErrInvalidCursor = errors.New("models: invalid cursor")
+	// Lifetime that the expiry policy does not offer to the user's role
+	ErrInvalidExpiry = errors.New("models: expiry not allowed")
+	// Edit that leaves the snippet as it was, so no revision is recorded
+	ErrNoChange = errors.New("models: nothing changed")
 )
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Never is stored as the expiry date of snippets that never expire, so that
+// every "expires > now" filter keeps working unchanged.
+var Never = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+	year = 365 * day
+)
+
+// ExpiryOption is one lifetime offered on the create form.
+type ExpiryOption struct {
+	Key      string        // Form value, e.g. "30m", "12h", "7d" or "never"
+	Duration time.Duration // 0 for never
+}
+
+// Label reads like "30 minutes", "1 week" or "Never".
+func (o ExpiryOption) Label() string {
+	d := o.Duration
+	unit, size := "minute", time.Minute
+	switch {
+	case d == 0:
+		return "Never"
+	case d%year == 0:
+		unit, size = "year", year
+	case d%week == 0:
+		unit, size = "week", week
+	case d%day == 0:
+		unit, size = "day", day
+	case d%time.Hour == 0:
+		unit, size = "hour", time.Hour
+	}
+	n := int(d / size)
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+// ExpiresAt is the expiry date of a snippet created at created.
+func (o ExpiryOption) ExpiresAt(created time.Time) time.Time {
+	if o.Duration == 0 {
+		return Never
+	}
+	return created.Add(o.Duration)
+}
+
+// ExpiryPolicy is the one place that decides which lifetimes snippets may
+// have. The create form, its validation and SnippetModel.Insert all read it.
+type ExpiryPolicy struct {
+	Options []ExpiryOption // In the order the form shows them
+	Default string         // Key of the preselected option
+	// Longest lifetime each role may pick, 0 for never expiring.
+	// Roles that are not listed have no limit.
+	MaxByRole map[string]time.Duration
+}
+
+// DefaultExpiryPolicy offers the original one day / week / year choice.
+var DefaultExpiryPolicy = &ExpiryPolicy{
+	Options: []ExpiryOption{{"365d", year}, {"7d", week}, {"1d", day}},
+	Default: "365d",
+}
+
+// ParseExpiryPolicy reads the policy from its flag form: options such as
+// "10m,1h,1d,7d,365d,never", the default option, and per-role limits
+// such as "user=7d,admin=never".
+func ParseExpiryPolicy(options, def, maxByRole string) (*ExpiryPolicy, error) {
+	p := &ExpiryPolicy{MaxByRole: map[string]time.Duration{}}
+	for _, key := range strings.Split(options, ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		d, err := parseLifetime(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.option(key); ok {
+			return nil, fmt.Errorf("models: expiry option %q listed twice", key)
+		}
+		p.Options = append(p.Options, ExpiryOption{Key: key, Duration: d})
+	}
+	p.Default = strings.ToLower(strings.TrimSpace(def))
+	if _, ok := p.option(p.Default); !ok {
+		return nil, fmt.Errorf("models: default expiry %q is not one of the options", def)
+	}
+	if strings.TrimSpace(maxByRole) == "" {
+		return p, nil
+	}
+	for _, pair := range strings.Split(maxByRole, ",") {
+		role, max, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || role == "" {
+			return nil, fmt.Errorf("models: bad role expiry limit %q, want role=duration", pair)
+		}
+		d, err := parseLifetime(strings.ToLower(max))
+		if err != nil {
+			return nil, err
+		}
+		p.MaxByRole[role] = d
+		if len(p.Allowed(role)) == 0 {
+			return nil, fmt.Errorf("models: expiry limit %q leaves role %q no option", max, role)
+		}
+	}
+	return p, nil
+}
+
+// parseLifetime reads "never" or a whole number of minutes, hours, days or weeks.
+func parseLifetime(s string) (time.Duration, error) {
+	if s == "never" {
+		return 0, nil
+	}
+	units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': day, 'w': week}
+	if len(s) < 2 || units[s[len(s)-1]] == 0 {
+		return 0, fmt.Errorf("models: bad expiry %q, want e.g. 30m, 12h, 7d, 2w or never", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("models: bad expiry %q, want e.g. 30m, 12h, 7d, 2w or never", s)
+	}
+	return time.Duration(n) * units[s[len(s)-1]], nil
+}
+
+func (p *ExpiryPolicy) option(key string) (ExpiryOption, bool) {
+	for _, o := range p.Options {
+		if o.Key == key {
+			return o, true
+		}
+	}
+	return ExpiryOption{}, false
+}
+
+// permits reports whether the role may pick option o.
+func (p *ExpiryPolicy) permits(o ExpiryOption, role string) bool {
+	max, limited := p.MaxByRole[role]
+	if !limited || max == 0 {
+		return true
+	}
+	return o.Duration != 0 && o.Duration <= max
+}
+
+// Allowed lists the options the role may pick.
+func (p *ExpiryPolicy) Allowed(role string) []ExpiryOption {
+	allowed := []ExpiryOption{}
+	for _, o := range p.Options {
+		if p.permits(o, role) {
+			allowed = append(allowed, o)
+		}
+	}
+	return allowed
+}
+
+// Lookup finds the option behind a form value, if the role may pick it.
+func (p *ExpiryPolicy) Lookup(key, role string) (ExpiryOption, bool) {
+	o, ok := p.option(key)
+	if !ok || !p.permits(o, role) {
+		return ExpiryOption{}, false
+	}
+	return o, true
+}
+
+// DefaultFor is the option preselected for the role: the policy default
+// when the role may pick it, the longest allowed lifetime otherwise.
+func (p *ExpiryPolicy) DefaultFor(role string) string {
+	if _, ok := p.Lookup(p.Default, role); ok {
+		return p.Default
+	}
+	best := ExpiryOption{Duration: -1}
+	for _, o := range p.Allowed(role) {
+		if o.Duration > best.Duration {
+			best = o
+		}
+	}
+	return best.Key
+}
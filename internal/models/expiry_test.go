@@ -0,0 +1,164 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpiryPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     string
+		def         string
+		maxByRole   string
+		wantKeys    []string
+		wantDefault string
+		wantErr     bool
+	}{
+		{"Flag defaults", "365d,7d,1d", "365d", "", []string{"365d", "7d", "1d"}, "365d", false},
+		{"Every unit and never", "10m, 1H,1d,2w,never", "NEVER", "", []string{"10m", "1h", "1d", "2w", "never"}, "never", false},
+		{"Role limits", "1h,1d,never", "1d", "user=1d, admin=never", []string{"1h", "1d", "never"}, "1d", false},
+		{"Bad unit", "10s", "10s", "", nil, "", true},
+		{"Year unit", "1y", "1y", "", nil, "", true},
+		{"No number", "d", "d", "", nil, "", true},
+		{"Zero", "0d", "0d", "", nil, "", true},
+		{"Negative", "-1d", "-1d", "", nil, "", true},
+		{"Empty option", "1d,,7d", "1d", "", nil, "", true},
+		{"Listed twice", "1d,7d,1d", "1d", "", nil, "", true},
+		{"Default not an option", "1d,7d", "365d", "", nil, "", true},
+		{"Limit without a role", "1d,7d", "7d", "=1d", nil, "", true},
+		{"Limit without a duration", "1d,7d", "7d", "user", nil, "", true},
+		{"Bad limit", "1d,7d", "7d", "user=1x", nil, "", true},
+		{"Limit below every option", "1d,7d", "7d", "user=1h", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseExpiryPolicy(tt.options, tt.def, tt.maxByRole)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got policy %+v; want an error", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, o := range p.Options {
+				keys = append(keys, o.Key)
+			}
+			if len(keys) != len(tt.wantKeys) {
+				t.Fatalf("got options %v; want %v", keys, tt.wantKeys)
+			}
+			for i := range keys {
+				if keys[i] != tt.wantKeys[i] {
+					t.Fatalf("got options %v; want %v", keys, tt.wantKeys)
+				}
+			}
+			if p.Default != tt.wantDefault {
+				t.Errorf("got default %q; want %q", p.Default, tt.wantDefault)
+			}
+		})
+	}
+}
+
+func TestExpiryPolicyLookup(t *testing.T) {
+	p, err := ParseExpiryPolicy("30m,1d,7d,never", "7d", "user=1d,admin=never")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		key    string
+		role   string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"Within the role limit", "1d", "user", day, true},
+		{"Above the role limit", "7d", "user", 0, false},
+		{"Never for a limited role", "never", "user", 0, false},
+		{"Never without a limit", "never", "admin", 0, true},
+		{"Unlisted role", "7d", "guest", week, true},
+		{"Unknown key", "2d", "admin", 0, false},
+		{"Empty key", "", "admin", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, ok := p.Lookup(tt.key, tt.role)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %t; want %t", ok, tt.wantOK)
+			}
+			if o.Duration != tt.want {
+				t.Errorf("got %v; want %v", o.Duration, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiryPolicyDefaultFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   string
+		def       string
+		maxByRole string
+		role      string
+		want      string
+	}{
+		{"Policy default", "1d,7d,365d", "365d", "", "user", "365d"},
+		{"Longest allowed below the limit", "1d,7d,365d", "365d", "user=7d", "user", "7d"},
+		{"Limit between options", "1d,7d,365d", "365d", "user=30d", "user", "7d"},
+		{"Never as default", "1d,never", "never", "user=1d", "admin", "never"},
+		{"Never out of reach", "1d,never", "never", "user=1d", "user", "1d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseExpiryPolicy(tt.options, tt.def, tt.maxByRole)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.DefaultFor(tt.role); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiryOptionLabel(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"1m", "1 minute"},
+		{"90m", "90 minutes"},
+		{"60m", "1 hour"},
+		{"12h", "12 hours"},
+		{"24h", "1 day"},
+		{"3d", "3 days"},
+		{"7d", "1 week"},
+		{"2w", "2 weeks"},
+		{"365d", "1 year"},
+		{"730d", "2 years"},
+		{"never", "Never"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			d, err := parseLifetime(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := (ExpiryOption{Key: tt.key, Duration: d}).Label(); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiryOptionExpiresAt(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := (ExpiryOption{Key: "1d", Duration: day}).ExpiresAt(created); !got.Equal(created.Add(day)) {
+		t.Errorf("1d: got %v; want %v", got, created.Add(day))
+	}
+	if got := (ExpiryOption{Key: "never"}).ExpiresAt(created); !got.Equal(Never) {
+		t.Errorf("never: got %v; want %v", got, Never)
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import "errors"
+
+// Limits enforced on the create form
+const (
+	MaxFiles          = 10
+	MaxFileNameLength = 100
+)
+
+// File is one named file of a snippet. The first one is the main file:
+// Snippet.Content and Snippet.Language mirror it, and revisions, editing and
+// search only cover it.
+type File struct {
+	Name     string
+	Content  string
+	Language string // Highlighting language, "" for plain text
+	// How sure the detector was of Language, 0 when a person picked it
+	LanguageConfidence float64
+}
+
+// File finds one of the snippet's files by name.
+func (s *Snippet) File(name string) (File, bool) {
+	for _, f := range s.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+var errNoFiles = errors.New("models: a snippet needs at least one file")
+
+// insertFiles stores a new snippet's files in order, from position 1.
+func (m *SnippetModel) insertFiles(tx dbtx, id int, files []File) error {
+	for i, f := range files {
+		stmt := `INSERT INTO snippet_files (snippet_id, position, name, language, language_confidence, content) VALUES (?, ?, ?, ?, ?, ?)`
+		_, err := tx.Exec(m.Dialect.rebind(stmt), id, i+1, f.Name, f.Language, nullConfidence(f.LanguageConfidence), f.Content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// files returns a snippet's files in order.
+func (m *SnippetModel) files(id int) ([]File, error) {
+	query := `SELECT name, content, language, COALESCE(language_confidence, 0) FROM snippet_files WHERE snippet_id = ? ORDER BY position`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	files := []File{}
+	for rows.Next() {
+		var f File
+		if err = rows.Scan(&f.Name, &f.Content, &f.Language, &f.LanguageConfidence); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
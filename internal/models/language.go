@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// nullConfidence stores the confidence of a language a person picked (0) as NULL.
+func nullConfidence(c float64) any {
+	if c <= 0 {
+		return nil
+	}
+	return c
+}
+
+// SetLanguage records the owner's choice of highlighting language for one
+// file, replacing the detector's guess. It returns ErrNoRecord if the snippet
+// or the file is gone.
+func (m *SnippetModel) SetLanguage(id int, name string, language string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Look the file up first rather than counting affected rows: MySQL
+	// reports 0 for an UPDATE that sets the language the file already has.
+	var position int
+	query := `SELECT f.position FROM snippet_files f JOIN snippets s ON s.id = f.snippet_id
+	WHERE f.snippet_id = ? AND f.name = ? AND s.expires > ? AND s.deleted IS NULL` + m.Dialect.forUpdate()
+	err = tx.QueryRow(m.Dialect.rebind(query), id, name, now()).Scan(&position)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+	stmt := `UPDATE snippet_files SET language = ?, language_confidence = NULL WHERE snippet_id = ? AND name = ?`
+	if _, err = tx.Exec(m.Dialect.rebind(stmt), language, id, name); err != nil {
+		return err
+	}
+	// Keep the snippet's copy in step when this is the main file
+	if position == 1 {
+		stmt = `UPDATE snippets SET language = ?, language_confidence = NULL WHERE id = ?`
+		if _, err = tx.Exec(m.Dialect.rebind(stmt), language, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one numbered schema change with its up and down SQL.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	Applied *time.Time // nil while the migration is pending
+}
+
+// MigrationModel applies the migrations found in Files (one sub-directory
+// per dialect, see the migrations package) and records them in schema_migrations.
+type MigrationModel struct {
+	DB      *sql.DB
+	Dialect Dialect
+	Files   fs.FS
+}
+
+// Status lists every known migration in version order, with Applied set for
+// the ones already recorded in schema_migrations.
+func (m *MigrationModel) Status() ([]*Migration, error) {
+	if err := m.createTable(); err != nil {
+		return nil, err
+	}
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := m.DB.Query(`SELECT version, applied FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, mg := range migrations {
+		if at, ok := applied[mg.Version]; ok {
+			mg.Applied = &at
+		}
+	}
+	return migrations, nil
+}
+
+// Up applies all pending migrations in order and returns the ones it ran.
+func (m *MigrationModel) Up() ([]*Migration, error) {
+	migrations, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	done := []*Migration{}
+	for _, mg := range migrations {
+		if mg.Applied != nil {
+			continue
+		}
+		stmt := `INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)`
+		err = m.run(mg.Up, stmt, mg.Version, mg.Name, now())
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", mg.Version, mg.Name, err)
+		}
+		done = append(done, mg)
+	}
+	return done, nil
+}
+
+// Down rolls back the most recently applied migration.
+// It returns ErrNoRecord when there is nothing left to roll back.
+func (m *MigrationModel) Down() (*Migration, error) {
+	migrations, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mg := migrations[i]
+		if mg.Applied == nil {
+			continue
+		}
+		stmt := `DELETE FROM schema_migrations WHERE version = ?`
+		err = m.run(mg.Down, stmt, mg.Version)
+		if err != nil {
+			return nil, fmt.Errorf("migration %04d_%s: %w", mg.Version, mg.Name, err)
+		}
+		return mg, nil
+	}
+	return nil, ErrNoRecord
+}
+
+// run executes the statements of one migration plus its bookkeeping statement
+// in a transaction. MySQL commits DDL implicitly, so there a failed migration
+// may be left half-applied; SQLite and Postgres roll it back as a whole.
+func (m *MigrationModel) run(script string, bookkeeping string, args ...any) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range splitStatements(script) {
+		if _, err = tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(m.Dialect.rebind(bookkeeping), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *MigrationModel) createTable() error {
+	timestamp := "DATETIME"
+	if m.Dialect == Postgres {
+		timestamp = "TIMESTAMP"
+	}
+	stmt := `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied ` + timestamp + ` NOT NULL
+)`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// load reads NNNN_name.up.sql / NNNN_name.down.sql pairs for the model's dialect.
+func (m *MigrationModel) load() ([]*Migration, error) {
+	dir := m.Dialect.String()
+	entries, err := fs.ReadDir(m.Files, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".sql"), ".")
+		if e.IsDir() || !ok || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("models: bad migration file name %q", e.Name())
+		}
+		body, err := fs.ReadFile(m.Files, dir+"/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mg, exists := byVersion[version]
+		if !exists {
+			mg = &Migration{Version: version, Name: name}
+			byVersion[version] = mg
+		}
+		switch direction {
+		case "up":
+			mg.Up = string(body)
+		case "down":
+			mg.Down = string(body)
+		default:
+			return nil, fmt.Errorf("models: bad migration file name %q", e.Name())
+		}
+	}
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		migrations = append(migrations, mg)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements breaks a script into single statements, since the MySQL
+// driver refuses multi-statement Exec calls. Semicolons inside a
+// BEGIN ... END; block (trigger bodies) do not end the statement.
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	inBlock := false
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		upper := strings.ToUpper(trimmed)
+		switch {
+		case strings.HasSuffix(upper, "BEGIN"):
+			inBlock = true
+		case inBlock && strings.HasPrefix(upper, "END"):
+			inBlock = false
+		}
+		if !inBlock && strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/iam-vl/snbox/internal/models"
+)
+
+// In-memory fake of models.CollectionStore: user 1 has collection 1,
+// holding snippet 1.
+var mockCollection = &models.Collection{
+	ID:         1,
+	OwnerID:    1,
+	OwnerName:  "Alice",
+	Name:       "Haiku",
+	Visibility: models.Public,
+	Created:    time.Now(),
+	Size:       1,
+	Snippets:   []*models.Snippet{mockSnippet},
+}
+
+type CollectionModel struct{}
+
+func (m *CollectionModel) Insert(ownerID int, name string, visibility models.Visibility) (int, error) {
+	return 2, nil
+}
+
+func (m *CollectionModel) Get(id int, viewerID int) (*models.Collection, error) {
+	switch id {
+	case 1:
+		return mockCollection, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *CollectionModel) GetShared(token string, viewerID int) (*models.Collection, error) {
+	return nil, models.ErrNoRecord
+}
+
+func (m *CollectionModel) Owned(userID int) ([]*models.Collection, error) {
+	if userID == mockCollection.OwnerID {
+		return []*models.Collection{mockCollection}, nil
+	}
+	return []*models.Collection{}, nil
+}
+
+func (m *CollectionModel) Add(id int, snippetID int) error {
+	return nil
+}
+
+func (m *CollectionModel) Remove(id int, snippetID int) error {
+	if id == 1 && snippetID == 1 {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
+func (m *CollectionModel) Move(id int, snippetID int, position int) error {
+	if id == 1 && snippetID == 1 {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
+var _ models.CollectionStore = (*CollectionModel)(nil)
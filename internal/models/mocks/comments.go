@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/iam-vl/snbox/internal/models"
+)
+
+// In-memory fake of models.CommentStore: snippet 1 has one comment by user 1.
+var mockComment = &models.Comment{
+	ID:         1,
+	SnippetID:  1,
+	AuthorID:   1,
+	AuthorName: "Alice",
+	Body:       "Nice haiku",
+	Created:    time.Now(),
+}
+
+type CommentModel struct{}
+
+func (m *CommentModel) Insert(n models.NewComment) (int, error) {
+	if n.ParentID != 0 && n.ParentID != mockComment.ID {
+		return 0, models.ErrNoRecord
+	}
+	return 2, nil
+}
+
+func (m *CommentModel) Get(id int) (*models.Comment, error) {
+	switch id {
+	case 1:
+		return mockComment, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *CommentModel) Thread(snippetID int) ([]*models.Comment, error) {
+	if snippetID == mockComment.SnippetID {
+		return []*models.Comment{mockComment}, nil
+	}
+	return []*models.Comment{}, nil
+}
+
+func (m *CommentModel) Update(id int, body string) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *CommentModel) Delete(id int) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+var _ models.CommentStore = (*CommentModel)(nil)
@@ -0,0 +1,223 @@
+package mocks
+
+import (
+	"strings"
+	"time"
+
+	"github.com/iam-vl/snbox/internal/models"
+)
+
+// In-memory fake of models.SnippetStore.
+// Always knows about the public snippet 1 and, both owned by user 1,
+// the private snippet 3 and the unlisted snippet 4.
+var mockSnippet = &models.Snippet{
+	ID:         1,
+	Title:      "An old silent pond",
+	Content:    "An old silent pond...",
+	Created:    time.Now(),
+	Expires:    time.Now(),
+	OwnerID:    1,
+	Visibility: models.Public,
+	Files:      []models.File{{Name: "snippet.txt", Content: "An old silent pond..."}},
+}
+
+var mockPrivateSnippet = &models.Snippet{
+	ID:         3,
+	Title:      "Over the wintry forest",
+	Content:    "Over the wintry forest...",
+	Created:    time.Now(),
+	Expires:    time.Now(),
+	OwnerID:    1,
+	Visibility: models.Private,
+	Files:      []models.File{{Name: "snippet.txt", Content: "Over the wintry forest..."}},
+}
+
+// MockShareToken opens the unlisted mock snippet through /s/:token.
+const MockShareToken = "mock-share-token"
+
+var mockUnlistedSnippet = &models.Snippet{
+	ID:         4,
+	Title:      "A world of dew",
+	Content:    "A world of dew...",
+	Created:    time.Now(),
+	Expires:    time.Now(),
+	OwnerID:    1,
+	Visibility: models.Unlisted,
+	ShareToken: MockShareToken,
+	Files:      []models.File{{Name: "snippet.txt", Content: "A world of dew..."}},
+}
+
+type SnippetModel struct{}
+
+var mockRevision = &models.Revision{
+	SnippetID:  1,
+	Number:     1,
+	Title:      mockSnippet.Title,
+	Content:    mockSnippet.Content,
+	AuthorID:   1,
+	AuthorName: "Alice",
+	Created:    mockSnippet.Created,
+}
+
+func (m *SnippetModel) Insert(n models.NewSnippet) (int, error) {
+	return 2, nil
+}
+
+func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
+	switch id {
+	case 1:
+		return mockSnippet, nil
+	case 3:
+		return mockPrivateSnippet, nil
+	case 4:
+		return mockUnlistedSnippet, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) GetShared(token string) (*models.Snippet, error) {
+	if token == MockShareToken {
+		return mockUnlistedSnippet, nil
+	}
+	return nil, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Latest10() ([]*models.Snippet, error) {
+	return []*models.Snippet{mockSnippet}, nil
+}
+
+func (m *SnippetModel) Page(opts models.ListOptions) (*models.SnippetPage, error) {
+	if opts.Cursor != "" || opts.Tag != "" {
+		return &models.SnippetPage{Snippets: []*models.Snippet{}}, nil
+	}
+	return &models.SnippetPage{Snippets: []*models.Snippet{mockSnippet}}, nil
+}
+
+// Search falls back to a case-insensitive substring match on the mock snippet.
+func (m *SnippetModel) Search(q string, page int, size int) (*models.SearchResults, error) {
+	results := &models.SearchResults{Snippets: []*models.Snippet{}, Terms: models.SearchTerms(q), Page: page}
+	if page != 1 || len(results.Terms) == 0 {
+		return results, nil
+	}
+	text := strings.ToLower(mockSnippet.Title + " " + mockSnippet.Content)
+	for _, term := range results.Terms {
+		if !strings.Contains(text, term) {
+			return results, nil
+		}
+	}
+	results.Snippets = append(results.Snippets, mockSnippet)
+	return results, nil
+}
+
+func (m *SnippetModel) Update(id int, authorID int, title string, content string) (int, error) {
+	switch id {
+	case 1:
+		if title == mockSnippet.Title && content == mockSnippet.Content {
+			return 0, models.ErrNoChange
+		}
+		return 2, nil
+	default:
+		return 0, models.ErrNoRecord
+	}
+}
+
+// Revisions gives every mock snippet a single revision by Alice.
+func (m *SnippetModel) Revisions(id int) ([]*models.Revision, error) {
+	r, err := m.Revision(id, 1)
+	if err != nil {
+		return nil, err
+	}
+	return []*models.Revision{r}, nil
+}
+
+func (m *SnippetModel) Revision(id int, n int) (*models.Revision, error) {
+	if n != 1 {
+		return nil, models.ErrNoRecord
+	}
+	if id == 1 {
+		return mockRevision, nil
+	}
+	s, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	r := *mockRevision
+	r.SnippetID, r.Title, r.Content, r.Created = s.ID, s.Title, s.Content, s.Created
+	return &r, nil
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Restore(id int, userID int) error {
+	return models.ErrNoRecord
+}
+
+// Unlock never succeeds: the mock snippet has no passphrase.
+func (m *SnippetModel) Unlock(id int, passphrase string) error {
+	switch id {
+	case 1:
+		return models.ErrInvalidCreds
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+// Consume fails like a snippet whose last view was already taken.
+func (m *SnippetModel) Consume(id int) (int, error) {
+	return 0, models.ErrNoRecord
+}
+
+func (m *SnippetModel) AddViews(counts map[int]int) error {
+	return nil
+}
+
+func (m *SnippetModel) SetLanguage(id int, name string, language string) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) LockComments(id int, locked bool) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Trash(userID int) ([]*models.Snippet, error) {
+	return []*models.Snippet{}, nil
+}
+
+func (m *SnippetModel) Owned(userID int) ([]*models.Snippet, error) {
+	if userID == mockSnippet.OwnerID {
+		return []*models.Snippet{mockUnlistedSnippet, mockPrivateSnippet, mockSnippet}, nil
+	}
+	return []*models.Snippet{}, nil
+}
+
+func (m *SnippetModel) PurgeTrash(limit int, dryRun bool) (int, error) {
+	return 0, nil
+}
+
+func (m *SnippetModel) DeleteExpired(limit int, dryRun bool) (int, error) {
+	return 0, nil
+}
+
+func (m *SnippetModel) TagCloud() ([]*models.TagCount, error) {
+	return []*models.TagCount{}, nil
+}
+
+var _ models.SnippetStore = (*SnippetModel)(nil)
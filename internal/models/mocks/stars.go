@@ -0,0 +1,29 @@
+package mocks
+
+import (
+	"github.com/iam-vl/snbox/internal/models"
+)
+
+// In-memory fake of models.StarStore: user 1 starred snippet 1.
+type StarModel struct{}
+
+func (m *StarModel) Star(userID, snippetID int) error {
+	return nil
+}
+
+func (m *StarModel) Unstar(userID, snippetID int) error {
+	return nil
+}
+
+func (m *StarModel) Starred(userID, snippetID int) (bool, error) {
+	return userID == 1 && snippetID == mockSnippet.ID, nil
+}
+
+func (m *StarModel) List(userID int) ([]*models.Snippet, error) {
+	if userID == 1 {
+		return []*models.Snippet{mockSnippet}, nil
+	}
+	return []*models.Snippet{}, nil
+}
+
+var _ models.StarStore = (*StarModel)(nil)
@@ -0,0 +1,49 @@
+package mocks
+
+import "github.com/iam-vl/snbox/internal/models"
+
+// In-memory fake of models.UserStore.
+// "dupe@example.com" is taken, "alice@example.com" / "pa$$word" logs in as
+// user 1 and "bob@example.com" / "pa$$word" as user 2.
+type UserModel struct{}
+
+func (m *UserModel) Insert(name, email, password string) error {
+	switch email {
+	case "dupe@example.com":
+		return models.ErrDuplicateEmail
+	default:
+		return nil
+	}
+}
+
+func (m *UserModel) Auth(email, password string) (int, error) {
+	if password == "pa$$word" {
+		switch email {
+		case "alice@example.com":
+			return 1, nil
+		case "bob@example.com":
+			return 2, nil
+		}
+	}
+	return 0, models.ErrInvalidCreds
+}
+
+func (m *UserModel) Exists(id int) (bool, error) {
+	switch id {
+	case 1, 2:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (m *UserModel) Role(id int) (string, error) {
+	switch id {
+	case 1, 2:
+		return "user", nil
+	default:
+		return "", models.ErrNoRecord
+	}
+}
+
+var _ models.UserStore = (*UserModel)(nil)
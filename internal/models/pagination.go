@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SnippetSort is a listing order for SnippetModel.Page.
+type SnippetSort string
+
+const (
+	SortNewest   SnippetSort = "newest"
+	SortOldest   SnippetSort = "oldest"
+	SortExpiring SnippetSort = "expiring" // Expiring soonest first
+	SortViews    SnippetSort = "views"    // Most viewed first
+)
+
+// ParseSort maps a ?sort= value to a SnippetSort, defaulting to newest first.
+func ParseSort(s string) (SnippetSort, bool) {
+	switch SnippetSort(s) {
+	case "", SortNewest:
+		return SortNewest, true
+	case SortOldest, SortExpiring, SortViews:
+		return SnippetSort(s), true
+	default:
+		return SortNewest, false
+	}
+}
+
+// Column, direction and keyset comparison for each order.
+// id breaks ties, so the (column, id) pair is unique and no row is skipped.
+func (s SnippetSort) keyset() (column, dir, cmp string) {
+	switch s {
+	case SortOldest:
+		return "created", "ASC", ">"
+	case SortExpiring:
+		return "expires", "ASC", ">"
+	case SortViews:
+		return "view_count", "DESC", "<"
+	default:
+		return "created", "DESC", "<"
+	}
+}
+
+// ListOptions selects one page of a snippet listing.
+type ListOptions struct {
+	Sort   SnippetSort
+	Cursor string // Empty for the first page
+	Size   int
+	Tag    string // Only snippets carrying this tag, if set
+}
+
+// SnippetPage is one page of a snippet listing.
+type SnippetPage struct {
+	Snippets []*Snippet
+	Next     string // Cursor for the following page, empty on the last one
+}
+
+// key returns the value of the sort column for s, as stored in a cursor.
+func (o SnippetSort) key(s *Snippet) string {
+	switch o {
+	case SortExpiring:
+		return s.Expires.UTC().Format(time.RFC3339)
+	case SortViews:
+		return strconv.Itoa(s.Views)
+	default:
+		return s.Created.UTC().Format(time.RFC3339)
+	}
+}
+
+// parseKey turns the key of a cursor back into a value of the sort column.
+func (o SnippetSort) parseKey(key string) (any, error) {
+	if o == SortViews {
+		return strconv.Atoi(key)
+	}
+	t, err := time.Parse(time.RFC3339, key)
+	return t.UTC(), err
+}
+
+// Cursors are opaque to clients: the sort key and id of the last row shown.
+func encodeCursor(key string, id int) string {
+	raw := key + "," + strconv.Itoa(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(sort SnippetSort, cursor string) (any, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	keyText, idText, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return nil, 0, ErrInvalidCursor
+	}
+	key, err := sort.parseKey(keyText)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(idText)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	return key, id, nil
+}
+
+// Page returns up to opts.Size live public snippets in the given order, starting after
+// opts.Cursor (or at the top when it is empty). It uses keyset pagination rather
+// than OFFSET, so deep pages cost the same as the first one and walk the
+// idx_snippets_created / idx_snippets_expires / idx_snippets_view_count indexes.
+// View counts keep moving, so a snippet read between two pages of the most
+// viewed listing may show up twice or not at all.
+func (m *SnippetModel) Page(opts ListOptions) (*SnippetPage, error) {
+	size := opts.Size
+	column, dir, cmp := opts.Sort.keyset()
+	where := `expires > ? AND deleted IS NULL AND visibility = 'public'`
+	args := []any{now()}
+	if opts.Tag != "" {
+		where += ` AND ` + taggedWith
+		args = append(args, opts.Tag)
+	}
+	if opts.Cursor != "" {
+		key, id, err := decodeCursor(opts.Sort, opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where += fmt.Sprintf(` AND (%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))`, column, cmp)
+		args = append(args, key, key, id)
+	}
+	// One extra row tells us whether there is a next page
+	query := `SELECT id, title, content, created, expires, view_count FROM snippets WHERE ` + where +
+		fmt.Sprintf(` ORDER BY %[1]s %[2]s, id %[2]s LIMIT ?`, column, dir)
+	args = append(args, size+1)
+
+	rows, err := m.DB.Query(m.Dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := &SnippetPage{Snippets: []*Snippet{}}
+	for rows.Next() {
+		s := &Snippet{}
+		if err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires, &s.Views); err != nil {
+			return nil, err
+		}
+		page.Snippets = append(page.Snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Snippets) > size {
+		page.Snippets = page.Snippets[:size]
+		last := page.Snippets[size-1]
+		page.Next = encodeCursor(opts.Sort.key(last), last.ID)
+	}
+	return page, nil
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	// Keys are stored in UTC whatever the zone the driver hands back
+	expires := time.Date(2024, 3, 8, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	s := &Snippet{ID: 42, Created: created, Expires: expires, Views: 1337}
+
+	tests := []struct {
+		sort    SnippetSort
+		wantKey any
+	}{
+		{SortNewest, created},
+		{SortOldest, created},
+		{SortExpiring, expires.UTC()},
+		{SortViews, 1337},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.sort), func(t *testing.T) {
+			cursor := encodeCursor(tt.sort.key(s), s.ID)
+			key, id, err := decodeCursor(tt.sort, cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != tt.wantKey {
+				t.Errorf("got key %v; want %v", key, tt.wantKey)
+			}
+			if id != 42 {
+				t.Errorf("got id %d; want 42", id)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	tests := []struct {
+		name   string
+		sort   SnippetSort
+		cursor string
+	}{
+		{"Not base64", SortNewest, "not a cursor!"},
+		{"Padded base64", SortNewest, base64.URLEncoding.EncodeToString([]byte("2024-03-01T12:30:00Z,1"))},
+		{"No separator", SortNewest, encode("2024-03-01T12:30:00Z")},
+		{"Bad time", SortNewest, encode("yesterday,1")},
+		{"Empty key", SortExpiring, encode(",1")},
+		{"Bad id", SortOldest, encode("2024-03-01T12:30:00Z,x")},
+		{"Missing id", SortOldest, encode("2024-03-01T12:30:00Z,")},
+		{"Time key for views", SortViews, encode("2024-03-01T12:30:00Z,1")},
+		{"Count key for dates", SortNewest, encode("1337,1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeCursor(tt.sort, tt.cursor)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got %v; want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+// TestSnippetModelPageTieBreak pages through snippets that share the same
+// sort key: id alone must then keep every one of them on exactly one page.
+func TestSnippetModelPageTieBreak(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect}
+		for i := 1; i <= 5; i++ {
+			_, err := m.Insert(NewSnippet{
+				Title:   fmt.Sprintf("Snippet %d", i),
+				Files:   []File{{Name: "snippet.txt", Content: "x"}},
+				Expires: "1d",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		created := now()
+		_, err := db.Exec(dialect.rebind(`UPDATE snippets SET created = ?, expires = ?`), created, created.Add(24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			sort SnippetSort
+			want []int
+		}{
+			{SortNewest, []int{5, 4, 3, 2, 1}},
+			{SortOldest, []int{1, 2, 3, 4, 5}},
+			{SortExpiring, []int{1, 2, 3, 4, 5}},
+			{SortViews, []int{5, 4, 3, 2, 1}},
+		}
+		for _, tt := range tests {
+			t.Run(string(tt.sort), func(t *testing.T) {
+				var ids []int
+				opts := ListOptions{Sort: tt.sort, Size: 2}
+				for pages := 0; ; pages++ {
+					if pages == 3 {
+						t.Fatalf("more than 3 pages, got %v so far", ids)
+					}
+					page, err := m.Page(opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, s := range page.Snippets {
+						ids = append(ids, s.ID)
+					}
+					if page.Next == "" {
+						break
+					}
+					opts.Cursor = page.Next
+				}
+				if !slices.Equal(ids, tt.want) {
+					t.Errorf("got %v; want %v", ids, tt.want)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hashPassphrase hashes a snippet passphrase the way UserModel.Insert hashes
+// account passwords. An empty passphrase means no lock and is stored as NULL.
+func hashPassphrase(passphrase string) (sql.NullString, error) {
+	if passphrase == "" {
+		return sql.NullString{}, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(passphrase), 12)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+// Unlock checks a passphrase against a live snippet's hash.
+// It returns ErrInvalidCreds when the passphrase is wrong or the snippet has none.
+func (m *SnippetModel) Unlock(id int, passphrase string) error {
+	var hash sql.NullString
+	query := `SELECT passphrase_hash FROM snippets WHERE id = ? AND expires > ? AND deleted IS NULL`
+	err := m.DB.QueryRow(m.Dialect.rebind(query), id, now()).Scan(&hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+	if !hash.Valid {
+		return ErrInvalidCreds
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(hash.String), []byte(passphrase))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCreds
+		}
+		return err
+	}
+	return nil
+}
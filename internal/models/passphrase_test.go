@@ -0,0 +1,44 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestSnippetModelUnlock(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect}
+		locked, err := m.Insert(NewSnippet{Title: "x", Files: []File{{Name: "x", Content: "x"}}, Expires: "1d", Passphrase: "open sesame"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		open, err := m.Insert(NewSnippet{Title: "x", Files: []File{{Name: "x", Content: "x"}}, Expires: "1d"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s, err := m.Get(locked); err != nil || !s.Locked {
+			t.Fatalf("got %+v, %v; want a locked snippet", s, err)
+		}
+
+		tests := []struct {
+			name       string
+			id         int
+			passphrase string
+			wantErr    error
+		}{
+			{"Right passphrase", locked, "open sesame", nil},
+			{"Wrong passphrase", locked, "open barley", ErrInvalidCreds},
+			{"Empty passphrase", locked, "", ErrInvalidCreds},
+			{"Snippet without a passphrase", open, "open sesame", ErrInvalidCreds},
+			{"Missing snippet", open + 1, "open sesame", ErrNoRecord},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := m.Unlock(tt.id, tt.passphrase); !errors.Is(err, tt.wantErr) {
+					t.Errorf("got %v; want %v", err, tt.wantErr)
+				}
+			})
+		}
+	})
+}
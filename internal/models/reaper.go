@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Used when SnippetModel.ExpiredRetention is left at zero
+const DefaultExpiredRetention = 7 * 24 * time.Hour
+
+// deleteBatch removes up to limit rows of table matching cond, lowest key first,
+// and returns how many went. Rows are picked with a SELECT and then deleted by
+// key, because DELETE ... LIMIT is MySQL-only. With dryRun nothing is deleted
+// and the full number of matching rows is returned instead.
+func (d Dialect) deleteBatch(db *sql.DB, table, key, cond string, limit int, dryRun bool, args ...any) (int, error) {
+	if dryRun {
+		var n int
+		query := `SELECT COUNT(*) FROM ` + table + ` WHERE ` + cond
+		err := db.QueryRow(d.rebind(query), args...).Scan(&n)
+		return n, err
+	}
+
+	query := `SELECT ` + key + ` FROM ` + table + ` WHERE ` + cond + ` ORDER BY ` + key + ` LIMIT ?`
+	rows, err := db.Query(d.rebind(query), append(args, limit)...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var keys []any
+	for rows.Next() {
+		var k any
+		if err = rows.Scan(&k); err != nil {
+			return 0, err
+		}
+		keys = append(keys, k)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	// Repeat cond so a row that changed since the SELECT (e.g. a restored snippet) survives
+	stmt := `DELETE FROM ` + table + ` WHERE ` + cond + ` AND ` + key + ` IN (?` + strings.Repeat(", ?", len(keys)-1) + `)`
+	result, err := db.Exec(d.rebind(stmt), append(args, keys...)...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// DeleteExpired hard deletes up to limit snippets that expired more than
+// ExpiredRetention ago, together with their revisions. Until then Owned keeps
+// listing them on the owner's dashboard.
+func (m *SnippetModel) DeleteExpired(limit int, dryRun bool) (int, error) {
+	retention := m.ExpiredRetention
+	if retention <= 0 {
+		retention = DefaultExpiredRetention
+	}
+	return m.Dialect.deleteBatch(m.DB, "snippets", "id", "expires <= ?", limit, dryRun, now().Add(-retention))
+}
+
+// SessionModel gives the reaper access to the scs sessions table,
+// whichever of mysqlstore, sqlite3store or postgresstore created it.
+type SessionModel struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// DeleteExpired removes up to limit sessions whose expiry has passed.
+func (m *SessionModel) DeleteExpired(limit int, dryRun bool) (int, error) {
+	if m.Dialect == SQLite {
+		// sqlite3store keeps expiry as a Julian day number
+		return m.Dialect.deleteBatch(m.DB, "sessions", "token", "expiry < julianday('now')", limit, dryRun)
+	}
+	return m.Dialect.deleteBatch(m.DB, "sessions", "token", "expiry < ?", limit, dryRun, now())
+}
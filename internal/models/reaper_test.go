@@ -0,0 +1,54 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestSnippetModelDeleteExpired(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect, ExpiredRetention: 7 * day}
+		users := &UserModel{DB: db, Dialect: dialect}
+		if err := users.Insert("Alice", "alice@example.com", "pa55word"); err != nil {
+			t.Fatal(err)
+		}
+		// Live, expired an hour ago, expired past the retention window
+		for _, expired := range []time.Duration{-time.Hour, time.Hour, 8 * day} {
+			id, err := m.Insert(NewSnippet{Title: "x", Files: []File{{Name: "x", Content: "x"}}, Expires: "1d", OwnerID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = db.Exec(dialect.rebind(`UPDATE snippets SET expires = ? WHERE id = ?`), now().Add(-expired), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		n, err := m.DeleteExpired(10, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("dry run: got %d; want 1", n)
+		}
+		if n, err = m.DeleteExpired(10, false); err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("got %d deleted; want 1", n)
+		}
+		owned, err := m.Owned(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, s := range owned {
+			ids = append(ids, s.ID)
+		}
+		// The snippet that expired within the window is still listed
+		if len(ids) != 2 || ids[0] != 2 || ids[1] != 1 {
+			t.Errorf("got owned %v; want [2 1]", ids)
+		}
+	})
+}
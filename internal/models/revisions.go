@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Revision is one immutable saved version of a snippet.
+// Revision 1 is written by Insert, every Update appends the next one.
+type Revision struct {
+	SnippetID  int
+	Number     int
+	Title      string
+	Content    string
+	AuthorID   int // 0 for snippets created before revisions were recorded
+	AuthorName string
+	Created    time.Time
+}
+
+// Update saves a new revision of a live snippet and makes it the current one.
+// The content replaces that of the main file.
+// It returns the number of the new revision, or ErrNoChange without
+// recording one if the title and content are those of the current revision.
+func (m *SnippetModel) Update(id int, authorID int, title string, content string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Read the snippet under a row lock: it serializes concurrent edits, so
+	// the MAX(revision) read below sees the previous writer's revision.
+	// Existence is checked here rather than with RowsAffected, which MySQL
+	// reports as 0 for an UPDATE that leaves the row as it was.
+	var oldTitle, oldContent string
+	query := `SELECT title, content FROM snippets WHERE id = ? AND expires > ? AND deleted IS NULL` + m.Dialect.forUpdate()
+	err = tx.QueryRow(m.Dialect.rebind(query), id, now()).Scan(&oldTitle, &oldContent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+	if title == oldTitle && content == oldContent {
+		return 0, ErrNoChange
+	}
+	stmt := `UPDATE snippets SET title = ?, content = ? WHERE id = ?`
+	if _, err = tx.Exec(m.Dialect.rebind(stmt), title, content, id); err != nil {
+		return 0, err
+	}
+	stmt = `UPDATE snippet_files SET content = ? WHERE snippet_id = ? AND position = 1`
+	if _, err = tx.Exec(m.Dialect.rebind(stmt), content, id); err != nil {
+		return 0, err
+	}
+
+	var n int
+	query = `SELECT COALESCE(MAX(revision), 0) + 1 FROM snippet_revisions WHERE snippet_id = ?`
+	if err = tx.QueryRow(m.Dialect.rebind(query), id).Scan(&n); err != nil {
+		return 0, err
+	}
+	if err = m.insertRevision(tx, id, n, title, content, authorID, now()); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+func (m *SnippetModel) insertRevision(tx dbtx, id, n int, title, content string, authorID int, created time.Time) error {
+	stmt := `INSERT INTO snippet_revisions (snippet_id, revision, title, content, author_id, created) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := tx.Exec(m.Dialect.rebind(stmt), id, n, title, content, nullID(authorID), created)
+	return err
+}
+
+const revisionColumns = `r.snippet_id, r.revision, r.title, r.content, COALESCE(r.author_id, 0), COALESCE(u.name, ''), r.created
+	FROM snippet_revisions r LEFT JOIN users u ON u.id = r.author_id`
+
+// Revisions returns every revision of a live snippet, oldest first.
+func (m *SnippetModel) Revisions(id int) ([]*Revision, error) {
+	query := `SELECT ` + revisionColumns + `
+	WHERE r.snippet_id = ? AND EXISTS (SELECT 1 FROM snippets s WHERE s.id = r.snippet_id AND s.expires > ? AND s.deleted IS NULL)
+	ORDER BY r.revision`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), id, now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	revisions := []*Revision{}
+	for rows.Next() {
+		r := &Revision{}
+		if err = rows.Scan(&r.SnippetID, &r.Number, &r.Title, &r.Content, &r.AuthorID, &r.AuthorName, &r.Created); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, ErrNoRecord
+	}
+	return revisions, nil
+}
+
+// Revision returns revision n of a live snippet.
+func (m *SnippetModel) Revision(id int, n int) (*Revision, error) {
+	query := `SELECT ` + revisionColumns + `
+	WHERE r.snippet_id = ? AND r.revision = ? AND EXISTS (SELECT 1 FROM snippets s WHERE s.id = r.snippet_id AND s.expires > ? AND s.deleted IS NULL)`
+	r := &Revision{}
+	err := m.DB.QueryRow(m.Dialect.rebind(query), id, n, now()).
+		Scan(&r.SnippetID, &r.Number, &r.Title, &r.Content, &r.AuthorID, &r.AuthorName, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchResults is one page of full-text search hits, best match first.
+type SearchResults struct {
+	Snippets []*Snippet
+	Terms    []string // Normalized query words, for highlighting
+	Page     int
+	HasNext  bool
+}
+
+// SearchTerms splits a query into lower-case words, dropping punctuation and
+// repeats. Only these words reach the database, so no user input is ever
+// parsed as full-text query syntax.
+func SearchTerms(q string) []string {
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, f := range strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[f] {
+			seen[f] = true
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// Search ranks live snippets against q using the database's own full-text
+// engine: a FULLTEXT index on MySQL, tsvector/ts_rank on Postgres and an FTS5
+// table with bm25 on SQLite. Relevance has no stable keyset, so unlike Page
+// this pages with OFFSET; deep result pages are rare enough for that.
+// Only public snippets without a passphrase or view limit are searched,
+// since the results page shows excerpts of the content.
+func (m *SnippetModel) Search(q string, page int, size int) (*SearchResults, error) {
+	results := &SearchResults{Snippets: []*Snippet{}, Terms: SearchTerms(q), Page: page}
+	if len(results.Terms) == 0 {
+		return results, nil
+	}
+
+	// Every query takes its LIMIT and OFFSET last; one extra row tells us
+	// whether there is a next page.
+	var query string
+	var args []any
+	switch m.Dialect {
+	case SQLite:
+		// Quote every term so FTS5 treats it as a plain string, ANDed together
+		match := `"` + strings.Join(results.Terms, `" "`) + `"`
+		query = `SELECT s.id, s.title, s.content, s.created, s.expires FROM snippets_fts
+		JOIN snippets s ON s.id = snippets_fts.rowid
+		WHERE snippets_fts MATCH ? AND s.expires > ? AND s.deleted IS NULL AND s.visibility = 'public' AND s.passphrase_hash IS NULL AND s.views_left IS NULL
+		ORDER BY bm25(snippets_fts), s.id DESC LIMIT ? OFFSET ?`
+		args = []any{match, now()}
+	case Postgres:
+		text := strings.Join(results.Terms, " ")
+		query = `SELECT id, title, content, created, expires FROM snippets
+		WHERE to_tsvector('english', title || ' ' || content) @@ plainto_tsquery('english', ?) AND expires > ? AND deleted IS NULL AND visibility = 'public' AND passphrase_hash IS NULL AND views_left IS NULL
+		ORDER BY ts_rank(to_tsvector('english', title || ' ' || content), plainto_tsquery('english', ?)) DESC, id DESC LIMIT ? OFFSET ?`
+		args = []any{text, now(), text}
+	default:
+		// Boolean mode with every term required, to AND them like the others.
+		// Terms are letters and digits only, so none can add an operator.
+		text := "+" + strings.Join(results.Terms, " +")
+		query = `SELECT id, title, content, created, expires FROM snippets
+		WHERE MATCH(title, content) AGAINST (? IN BOOLEAN MODE) AND expires > ? AND deleted IS NULL AND visibility = 'public' AND passphrase_hash IS NULL AND views_left IS NULL
+		ORDER BY MATCH(title, content) AGAINST (? IN BOOLEAN MODE) DESC, id DESC LIMIT ? OFFSET ?`
+		args = []any{text, now(), text}
+	}
+	args = append(args, size+1, (page-1)*size)
+
+	rows, err := m.DB.Query(m.Dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s := &Snippet{}
+		if err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires); err != nil {
+			return nil, err
+		}
+		results.Snippets = append(results.Snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results.Snippets) > size {
+		results.Snippets = results.Snippets[:size]
+		results.HasNext = true
+	}
+	return results, nil
+}
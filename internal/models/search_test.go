@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+)
+
+func TestSnippetModelSearch(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect}
+		for _, s := range []struct{ title, content string }{
+			{"An old silent pond", "A frog jumps into the pond"},
+			{"Pond life", "Goldfish and water lilies"},
+			{"Autumn moonlight", "A worm digs silently into the chestnut"},
+		} {
+			_, err := m.Insert(NewSnippet{Title: s.title, Files: []File{{Name: "haiku.txt", Content: s.content}}, Expires: "1d"})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		tests := []struct {
+			name string
+			q    string
+			want []int
+		}{
+			{"One term", "pond", []int{1, 2}},
+			// Every backend requires all the terms
+			{"All terms", "pond frog", []int{1}},
+			{"One term missing", "pond chestnut", []int{}},
+			{"Case and punctuation", "GOLDFISH!", []int{2}},
+			{"No terms", "?!", []int{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				results, err := m.Search(tt.q, 1, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids := []int{}
+				for _, s := range results.Snippets {
+					ids = append(ids, s.ID)
+				}
+				slices.Sort(ids)
+				if !slices.Equal(ids, tt.want) {
+					t.Errorf("got %v; want %v", ids, tt.want)
+				}
+			})
+		}
+	})
+}
@@ -3,7 +3,6 @@ package models
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"time"
 )
 
@@ -13,33 +12,166 @@ type Snippet struct {
 	Content string
 	Created time.Time
 	Expires time.Time
+	Deleted time.Time // Only set for snippets listed in the trash
+	Tags    []string  // Only set by Get
+	OwnerID int       // 0 for snippets created before owners were recorded
+	// Visibility is Public unless the owner chose otherwise.
+	// ShareToken is only set for Unlisted snippets.
+	Visibility Visibility
+	ShareToken string
+	Locked     bool // Protected by a passphrase, see Unlock
+	ViewsLeft  int  // 0 when views are unlimited, see Consume
+	// Content and Language are those of the main file, Files[0]
+	Language           string // Highlighting language, "" for plain text
+	LanguageConfidence float64
+	Files              []File // Only set by Get
+	ForkedFrom         int    // Parent snippet, 0 for originals
+	Forks              int    // Live forks of this snippet, only set by Get
+	CommentsLocked     bool   // The owner closed the discussion, see LockComments
+	Stars              int    // Users who starred the snippet, only set by Get and StarModel.List
+	Views              int    // Page reads flushed so far, see AddViews; set by Get and Page
 }
+
+// Expired reports whether the snippet is past its expiry date.
+// Only Owned returns such snippets.
+func (s *Snippet) Expired() bool {
+	return !s.Expires.After(time.Now())
+}
+
+// NewSnippet is what Insert needs to create a snippet.
+type NewSnippet struct {
+	Title      string
+	Files      []File   // At least one, the first is the main file
+	Expires    string   // Key of an ExpiryPolicy option
+	Role       string   // Role of the owner, for the policy's per-role limit
+	OwnerID    int      // Also the author of revision 1
+	Tags       []string // Normalized with ParseTags
+	Visibility Visibility
+	Passphrase string // Optional, locks the snippet
+	MaxViews   int    // Views before the snippet destroys itself, 0 for no limit
+	// Parent snippet, 0 for originals. Without a Passphrase of its own
+	// a fork keeps the parent's one.
+	ForkedFrom int
+}
+
+// SnippetStore describes the snippet operations the web app depends on.
+// SnippetModel implements it on top of *sql.DB, but any other backend
+// (or an in-memory fake) can be plugged in instead.
+type SnippetStore interface {
+	Insert(n NewSnippet) (int, error)
+	Get(id int) (*Snippet, error)
+	GetShared(token string) (*Snippet, error)
+	Latest10() ([]*Snippet, error)
+	Page(opts ListOptions) (*SnippetPage, error)
+	Search(q string, page int, size int) (*SearchResults, error)
+	Update(id int, authorID int, title string, content string) (int, error)
+	Revisions(id int) ([]*Revision, error)
+	Revision(id int, n int) (*Revision, error)
+	Delete(id int) error
+	Restore(id int, userID int) error
+	Unlock(id int, passphrase string) error
+	Consume(id int) (int, error)
+	AddViews(counts map[int]int) error
+	SetLanguage(id int, name string, language string) error
+	LockComments(id int, locked bool) error
+	Trash(userID int) ([]*Snippet, error)
+	Owned(userID int) ([]*Snippet, error)
+	PurgeTrash(limit int, dryRun bool) (int, error)
+	DeleteExpired(limit int, dryRun bool) (int, error)
+	TagCloud() ([]*TagCount, error)
+}
+
 type SnippetModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Dialect Dialect
+	// Lifetimes Insert accepts; DefaultExpiryPolicy when nil
+	Expiry *ExpiryPolicy
+	// How long a deleted snippet stays restorable before PurgeTrash removes it
+	TrashRetention time.Duration
+	// How long an expired snippet stays listed before DeleteExpired removes it
+	ExpiredRetention time.Duration
 }
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	query := `INSERT INTO snippets (title, content, created, expires) VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
-	// Use the Exec method on the embedded connection pool to execute the statement
-	// Perfectly fine to ignore the res, if you don't need it
-	result, err := m.DB.Exec(query, title, content, expires)
-	fmt.Printf("Result type: %T\n", result)
-	fmt.Printf("Result val: %+v\n", result)
+// Insert stores a new snippet together with its files, first revision and tags.
+// Unlisted snippets get a fresh share token, locked ones a passphrase hash.
+// It returns ErrInvalidExpiry if the expiry policy does not offer n.Expires to n.Role,
+// and ErrNoRecord if n.ForkedFrom names a snippet that no longer exists.
+func (m *SnippetModel) Insert(n NewSnippet) (int, error) {
+	policy := m.Expiry
+	if policy == nil {
+		policy = DefaultExpiryPolicy
+	}
+	expiry, ok := policy.Lookup(n.Expires, n.Role)
+	if !ok {
+		return 0, ErrInvalidExpiry
+	}
+	if len(n.Files) == 0 {
+		return 0, errNoFiles
+	}
+	main := n.Files[0]
+	if n.Visibility == "" {
+		n.Visibility = Public
+	}
+	var token sql.NullString
+	if n.Visibility == Unlisted {
+		t, err := newShareToken()
+		if err != nil {
+			return 0, err
+		}
+		token = sql.NullString{String: t, Valid: true}
+	}
+	// Hash before the transaction starts: bcrypt is deliberately slow
+	hash, err := hashPassphrase(n.Passphrase)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := m.DB.Begin()
 	if err != nil {
 		return 0, err
 	}
-	// get the new record's id
-	id, err := result.LastInsertId()
+	defer tx.Rollback()
+
+	if n.ForkedFrom != 0 && n.Passphrase == "" {
+		query := `SELECT passphrase_hash FROM snippets WHERE id = ?`
+		if err = tx.QueryRow(m.Dialect.rebind(query), n.ForkedFrom).Scan(&hash); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, ErrNoRecord
+			}
+			return 0, err
+		}
+	}
+
+	query := `INSERT INTO snippets (title, content, created, expires, owner_id, visibility, share_token, passphrase_hash, views_left,
+	language, language_confidence, forked_from)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	created := now()
+	id, err := m.Dialect.insert(tx, query, n.Title, main.Content, created, expiry.ExpiresAt(created),
+		nullID(n.OwnerID), n.Visibility, token, hash, nullViews(n.MaxViews), main.Language, nullConfidence(main.LanguageConfidence),
+		nullID(n.ForkedFrom))
 	if err != nil {
 		return 0, err
 	}
-	return int(id), nil
+	if err = m.insertFiles(tx, id, n.Files); err != nil {
+		return 0, err
+	}
+	if err = m.insertRevision(tx, id, 1, n.Title, main.Content, n.OwnerID, created); err != nil {
+		return 0, err
+	}
+	if err = m.setTags(tx, id, n.Tags); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 func (m *SnippetModel) Get(id int) (*Snippet, error) {
-	query := `SELECT id, title, content, created, expires FROM snippets WHERE expires > UTC_TIMESTAMP() AND id = ?`
-	row := m.DB.QueryRow(query, id)
+	query := `SELECT id, title, content, created, expires, COALESCE(owner_id, 0), visibility, COALESCE(share_token, ''),
+	passphrase_hash IS NOT NULL, COALESCE(views_left, 0), language,
+	COALESCE(language_confidence, 0), COALESCE(forked_from, 0),
+	(SELECT COUNT(*) FROM snippets f WHERE f.forked_from = snippets.id AND f.expires > ? AND f.deleted IS NULL),
+	comments_locked IS NOT NULL, (SELECT COUNT(*) FROM stars WHERE stars.snippet_id = snippets.id), view_count
+	FROM snippets WHERE expires > ? AND deleted IS NULL AND id = ?`
+	row := m.DB.QueryRow(m.Dialect.rebind(query), now(), now(), id)
 	s := &Snippet{}
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires, &s.OwnerID, &s.Visibility, &s.ShareToken, &s.Locked, &s.ViewsLeft, &s.Language, &s.LanguageConfidence, &s.ForkedFrom, &s.Forks, &s.CommentsLocked, &s.Stars, &s.Views)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNoRecord
@@ -47,12 +179,20 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 			return nil, err
 		}
 	}
+	s.Tags, err = m.tags(id)
+	if err != nil {
+		return nil, err
+	}
+	s.Files, err = m.files(id)
+	if err != nil {
+		return nil, err
+	}
 	return s, nil
 }
 
 func (m *SnippetModel) Latest10() ([]*Snippet, error) {
-	query := `SELECT id, title, content, created, expires FROM snippets WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
-	rows, err := m.DB.Query(query)
+	query := `SELECT id, title, content, created, expires FROM snippets WHERE expires > ? AND deleted IS NULL AND visibility = 'public' ORDER BY id DESC LIMIT 10`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), now())
 	if err != nil {
 		return nil, err
 	}
@@ -71,3 +211,27 @@ func (m *SnippetModel) Latest10() ([]*Snippet, error) {
 	}
 	return snippets, nil
 }
+
+// Owned lists every snippet of the user that is not in the trash,
+// expired ones included, newest first.
+func (m *SnippetModel) Owned(userID int) ([]*Snippet, error) {
+	query := `SELECT id, title, content, created, expires, visibility, COALESCE(share_token, ''), passphrase_hash IS NOT NULL,
+	COALESCE(views_left, 0) FROM snippets WHERE owner_id = ? AND deleted IS NULL ORDER BY created DESC, id DESC`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{OwnerID: userID}
+		if err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires, &s.Visibility, &s.ShareToken, &s.Locked, &s.ViewsLeft); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestSnippetModelInsertGet(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect}
+		id, err := m.Insert(NewSnippet{
+			Title: "An old silent pond",
+			Files: []File{
+				{Name: "haiku.txt", Content: "An old silent pond..."},
+				{Name: "notes.md", Content: "Basho", Language: "markdown"},
+			},
+			Expires: "7d",
+			Tags:    []string{"poetry", "basho"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Title != "An old silent pond" || s.Content != "An old silent pond..." {
+			t.Errorf("got %q, %q; want the title and main file content", s.Title, s.Content)
+		}
+		if s.Visibility != Public {
+			t.Errorf("got visibility %q; want %q", s.Visibility, Public)
+		}
+		if got := len(s.Files); got != 2 || s.Files[1].Name != "notes.md" {
+			t.Errorf("got files %+v; want haiku.txt and notes.md", s.Files)
+		}
+		if want := []string{"basho", "poetry"}; !slices.Equal(s.Tags, want) {
+			t.Errorf("got tags %v; want %v", s.Tags, want)
+		}
+		if d := s.Expires.Sub(s.Created); d.Hours() != 7*24 {
+			t.Errorf("got lifetime %v; want 168h", d)
+		}
+
+		if _, err = m.Get(id + 1); !errors.Is(err, ErrNoRecord) {
+			t.Errorf("Get of a missing snippet: got %v; want %v", err, ErrNoRecord)
+		}
+		_, err = m.Insert(NewSnippet{Title: "x", Files: []File{{Name: "x", Content: "x"}}, Expires: "never"})
+		if !errors.Is(err, ErrInvalidExpiry) {
+			t.Errorf("Insert with an expiry users may not pick: got %v; want %v", err, ErrInvalidExpiry)
+		}
+	})
+}
+
+func TestSnippetModelLatest10(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect}
+		for i := 1; i <= 12; i++ {
+			visibility := Public
+			if i == 12 {
+				visibility = Private
+			}
+			_, err := m.Insert(NewSnippet{
+				Title:      fmt.Sprintf("Snippet %d", i),
+				Files:      []File{{Name: "snippet.txt", Content: "x"}},
+				Expires:    "1d",
+				Visibility: visibility,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		snippets, err := m.Latest10()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, s := range snippets {
+			ids = append(ids, s.ID)
+		}
+		// Newest first, without the private snippet 12
+		if want := []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}; !slices.Equal(ids, want) {
+			t.Errorf("got %v; want %v", ids, want)
+		}
+	})
+}
+
+func TestSnippetModelUpdate(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect}
+		id, err := m.Insert(NewSnippet{Title: "Title", Files: []File{{Name: "snippet.txt", Content: "one"}}, Expires: "1d"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			name    string
+			id      int
+			title   string
+			content string
+			want    int
+			wantErr error
+		}{
+			{"New content", id, "Title", "two", 2, nil},
+			{"Unchanged", id, "Title", "two", 0, ErrNoChange},
+			{"New title", id, "Other title", "two", 3, nil},
+			{"Missing snippet", id + 1, "Title", "two", 0, ErrNoRecord},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				n, err := m.Update(tt.id, 0, tt.title, tt.content)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v; want %v", err, tt.wantErr)
+				}
+				if n != tt.want {
+					t.Errorf("got revision %d; want %d", n, tt.want)
+				}
+			})
+		}
+		revisions, err := m.Revisions(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 3 {
+			t.Errorf("got %d revisions; want 3", len(revisions))
+		}
+	})
+}
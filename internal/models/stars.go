@@ -0,0 +1,74 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// StarStore describes the star operations the web app depends on.
+type StarStore interface {
+	Star(userID, snippetID int) error
+	Unstar(userID, snippetID int) error
+	Starred(userID, snippetID int) (bool, error)
+	List(userID int) ([]*Snippet, error)
+}
+
+type StarModel struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// Star bookmarks a snippet for the user. Starring it again changes nothing:
+// the stars primary key turns the second insert into a duplicate.
+func (m *StarModel) Star(userID, snippetID int) error {
+	stmt := `INSERT INTO stars (user_id, snippet_id, created) VALUES (?, ?, ?)`
+	_, err := m.DB.Exec(m.Dialect.rebind(stmt), userID, snippetID, now())
+	if err != nil && !m.Dialect.isDuplicate(err) {
+		return err
+	}
+	return nil
+}
+
+// Unstar removes a star, if the user had given one.
+func (m *StarModel) Unstar(userID, snippetID int) error {
+	stmt := `DELETE FROM stars WHERE user_id = ? AND snippet_id = ?`
+	_, err := m.DB.Exec(m.Dialect.rebind(stmt), userID, snippetID)
+	return err
+}
+
+// Starred reports whether the user starred the snippet.
+func (m *StarModel) Starred(userID, snippetID int) (bool, error) {
+	var n int
+	query := `SELECT COUNT(*) FROM stars WHERE user_id = ? AND snippet_id = ?`
+	if err := m.DB.QueryRow(m.Dialect.rebind(query), userID, snippetID).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// List returns the user's starred snippets, most recently starred first.
+// Snippets that have expired, gone to the trash or are private to somebody
+// else are left out.
+func (m *StarModel) List(userID int) ([]*Snippet, error) {
+	query := `SELECT s.id, s.title, s.created, s.expires, COALESCE(s.owner_id, 0), s.visibility, COALESCE(s.share_token, ''),
+	(SELECT COUNT(*) FROM stars c WHERE c.snippet_id = s.id)
+	FROM stars st JOIN snippets s ON s.id = st.snippet_id
+	WHERE st.user_id = ? AND s.expires > ? AND s.deleted IS NULL AND (s.visibility <> 'private' OR s.owner_id = ?)
+	ORDER BY st.created DESC, s.id DESC`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), userID, now(), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{}
+		if err = rows.Scan(&s.ID, &s.Title, &s.Created, &s.Expires, &s.OwnerID, &s.Visibility, &s.ShareToken, &s.Stars); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
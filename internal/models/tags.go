@@ -0,0 +1,112 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// Limits enforced on the create form
+const (
+	MaxTags      = 5
+	MaxTagLength = 30
+)
+
+// TagCount is one entry of the tag cloud.
+type TagCount struct {
+	Name   string
+	Count  int // Live snippets carrying the tag
+	Weight int // 1-5, relative to the most used tag
+}
+
+// ParseTags splits "go, http  Testing" into ["go" "http" "testing"]:
+// commas or spaces separate tags, case is folded and repeats are dropped.
+func ParseTags(s string) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, f := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}) {
+		if !seen[f] {
+			seen[f] = true
+			tags = append(tags, f)
+		}
+	}
+	return tags
+}
+
+// taggedWith restricts a snippets query to the ones carrying a tag (one ? for the name).
+const taggedWith = `EXISTS (SELECT 1 FROM snippet_tags st JOIN tags t ON t.id = st.tag_id WHERE st.snippet_id = snippets.id AND t.name = ?)`
+
+// setTags links a snippet to its tags, creating tags seen for the first time.
+func (m *SnippetModel) setTags(tx dbtx, id int, tags []string) error {
+	for _, name := range tags {
+		var tagID int
+		query := `SELECT id FROM tags WHERE name = ?`
+		err := tx.QueryRow(m.Dialect.rebind(query), name).Scan(&tagID)
+		if errors.Is(err, sql.ErrNoRows) {
+			tagID, err = m.Dialect.insert(tx, `INSERT INTO tags (name) VALUES (?)`, name)
+		}
+		if err != nil {
+			return err
+		}
+		stmt := `INSERT INTO snippet_tags (snippet_id, tag_id) VALUES (?, ?)`
+		if _, err = tx.Exec(m.Dialect.rebind(stmt), id, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tags returns the names of a snippet's tags in alphabetical order.
+func (m *SnippetModel) tags(id int) ([]string, error) {
+	query := `SELECT t.name FROM tags t JOIN snippet_tags st ON st.tag_id = t.id WHERE st.snippet_id = ? ORDER BY t.name`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []string{}
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+	return tags, rows.Err()
+}
+
+// TagCloud counts the live public snippets carrying each tag, alphabetically.
+// Tags whose snippets have all expired, been deleted or are hidden are left out.
+func (m *SnippetModel) TagCloud() ([]*TagCount, error) {
+	query := `SELECT t.name, COUNT(*) FROM tags t
+	JOIN snippet_tags st ON st.tag_id = t.id
+	JOIN snippets s ON s.id = st.snippet_id
+	WHERE s.expires > ? AND s.deleted IS NULL AND s.visibility = 'public'
+	GROUP BY t.name ORDER BY t.name`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cloud := []*TagCount{}
+	most := 0
+	for rows.Next() {
+		tc := &TagCount{}
+		if err = rows.Scan(&tc.Name, &tc.Count); err != nil {
+			return nil, err
+		}
+		if tc.Count > most {
+			most = tc.Count
+		}
+		cloud = append(cloud, tc)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, tc := range cloud {
+		tc.Weight = 1 + 4*(tc.Count-1)/max(most-1, 1)
+	}
+	return cloud, nil
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/iam-vl/snbox/migrations"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// The model tests always run against a throwaway SQLite file. Set these to
+// the DSN of a scratch database to run them against MySQL or Postgres too;
+// every table in it is dropped when a test finishes.
+const (
+	mysqlDsnEnv    = "SNBOX_TEST_MYSQL_DSN"
+	postgresDsnEnv = "SNBOX_TEST_POSTGRES_DSN"
+)
+
+// forEachDialect runs fn as a subtest for every dialect with a database
+// available, each time on a freshly migrated schema.
+func forEachDialect(t *testing.T, fn func(t *testing.T, db *sql.DB, dialect Dialect)) {
+	t.Helper()
+	backends := []struct {
+		dialect Dialect
+		env     string
+	}{
+		{SQLite, ""},
+		{MySQL, mysqlDsnEnv},
+		{Postgres, postgresDsnEnv},
+	}
+	for _, b := range backends {
+		t.Run(b.dialect.String(), func(t *testing.T) {
+			var dsn string
+			if b.dialect == SQLite {
+				dsn = "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)&_time_format=sqlite&_txlock=immediate"
+			} else if dsn = os.Getenv(b.env); dsn == "" {
+				t.Skipf("%s not set", b.env)
+			}
+			fn(t, newTestDB(t, b.dialect, dsn), b.dialect)
+		})
+	}
+}
+
+// newTestDB opens the database, applies every migration and rolls them all
+// back again once the test is over.
+func newTestDB(t *testing.T, dialect Dialect, dsn string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(dialect.DriverName(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrationModel := &MigrationModel{DB: db, Dialect: dialect, Files: migrations.Files}
+	if _, err = migrationModel.Up(); err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		defer db.Close()
+		for {
+			_, err := migrationModel.Down()
+			if errors.Is(err, ErrNoRecord) {
+				break
+			}
+			if err != nil {
+				t.Error(err)
+				break
+			}
+		}
+		if _, err := db.Exec(`DROP TABLE schema_migrations`); err != nil {
+			t.Error(err)
+		}
+	})
+	return db
+}
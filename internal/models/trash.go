@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Used when SnippetModel.TrashRetention is left at zero
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+func (m *SnippetModel) trashCutoff() time.Time {
+	retention := m.TrashRetention
+	if retention <= 0 {
+		retention = DefaultTrashRetention
+	}
+	return now().Add(-retention)
+}
+
+// Delete moves a snippet to the trash. Get, Latest10 and friends stop
+// returning it straight away; the row itself stays until PurgeTrash.
+func (m *SnippetModel) Delete(id int) error {
+	stmt := `UPDATE snippets SET deleted = ? WHERE id = ? AND deleted IS NULL`
+	result, err := m.DB.Exec(m.Dialect.rebind(stmt), now(), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// ownedBy matches the user's snippets.
+const ownedBy = `owner_id = ?`
+
+// Restore takes one of the user's snippets back out of the trash,
+// as long as the retention window has not passed yet.
+func (m *SnippetModel) Restore(id int, userID int) error {
+	stmt := `UPDATE snippets SET deleted = NULL WHERE id = ? AND deleted > ? AND ` + ownedBy
+	result, err := m.DB.Exec(m.Dialect.rebind(stmt), id, m.trashCutoff(), userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Trash lists the user's restorable snippets, most recently deleted first.
+func (m *SnippetModel) Trash(userID int) ([]*Snippet, error) {
+	query := `SELECT id, title, content, created, expires, deleted FROM snippets
+	WHERE deleted > ? AND expires > ? AND ` + ownedBy + `
+	ORDER BY deleted DESC`
+	rows, err := m.DB.Query(m.Dialect.rebind(query), m.trashCutoff(), now(), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	snippets := []*Snippet{}
+	for rows.Next() {
+		s := &Snippet{}
+		if err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires, &s.Deleted); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// PurgeTrash hard deletes up to limit snippets that have been in the trash for
+// longer than the retention window. Their revisions go with them (ON DELETE CASCADE).
+func (m *SnippetModel) PurgeTrash(limit int, dryRun bool) (int, error) {
+	return m.Dialect.deleteBatch(m.DB, "snippets", "id", "deleted <= ?", limit, dryRun, m.trashCutoff())
+}
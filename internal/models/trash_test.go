@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSnippetModelTrash(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect, TrashRetention: 30 * day}
+		users := &UserModel{DB: db, Dialect: dialect}
+		for _, email := range []string{"alice@example.com", "bob@example.com"} {
+			if err := users.Insert(email, email, "pa55word"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var ids []int
+		for i := 0; i < 3; i++ {
+			id, err := m.Insert(NewSnippet{Title: "x", Files: []File{{Name: "x", Content: "x"}}, Expires: "1d", OwnerID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		trashed, kept, old := ids[0], ids[1], ids[2]
+		trash := func(userID int) []int {
+			snippets, err := m.Trash(userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []int{}
+			for _, s := range snippets {
+				got = append(got, s.ID)
+			}
+			return got
+		}
+
+		t.Run("Delete hides the snippet", func(t *testing.T) {
+			if err := m.Delete(trashed); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.Get(trashed); !errors.Is(err, ErrNoRecord) {
+				t.Errorf("Get: got %v; want %v", err, ErrNoRecord)
+			}
+			latest, err := m.Latest10()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range latest {
+				if s.ID == trashed {
+					t.Error("Latest10 lists the trashed snippet")
+				}
+			}
+			if err := m.Delete(trashed); !errors.Is(err, ErrNoRecord) {
+				t.Errorf("deleting it again: got %v; want %v", err, ErrNoRecord)
+			}
+			if got := trash(1); !slices.Equal(got, []int{trashed}) {
+				t.Errorf("owner's trash: got %v; want [%d]", got, trashed)
+			}
+			if got := trash(2); len(got) != 0 {
+				t.Errorf("another user's trash: got %v; want none", got)
+			}
+		})
+
+		t.Run("Only the owner restores", func(t *testing.T) {
+			if err := m.Restore(trashed, 2); !errors.Is(err, ErrNoRecord) {
+				t.Errorf("another user: got %v; want %v", err, ErrNoRecord)
+			}
+			if err := m.Restore(trashed, 1); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.Get(trashed); err != nil {
+				t.Errorf("Get after Restore: %v", err)
+			}
+			if err := m.Restore(trashed, 1); !errors.Is(err, ErrNoRecord) {
+				t.Errorf("restoring a live snippet: got %v; want %v", err, ErrNoRecord)
+			}
+		})
+
+		t.Run("PurgeTrash honours TrashRetention", func(t *testing.T) {
+			for _, id := range []int{kept, old} {
+				if err := m.Delete(id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// One day past the 30 day window
+			_, err := db.Exec(dialect.rebind(`UPDATE snippets SET deleted = ? WHERE id = ?`), now().Add(-31*day), old)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = m.Restore(old, 1); !errors.Is(err, ErrNoRecord) {
+				t.Errorf("restoring past the window: got %v; want %v", err, ErrNoRecord)
+			}
+			if got := trash(1); !slices.Equal(got, []int{kept}) {
+				t.Errorf("trash: got %v; want [%d]", got, kept)
+			}
+			n, err := m.PurgeTrash(10, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Errorf("dry run: got %d; want 1", n)
+			}
+			if n, err = m.PurgeTrash(10, false); err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Errorf("got %d purged; want 1", n)
+			}
+			var left []int
+			rows, err := db.Query(`SELECT id FROM snippets ORDER BY id`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var id int
+				if err = rows.Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				left = append(left, id)
+			}
+			if want := []int{trashed, kept}; !slices.Equal(left, want) {
+				t.Errorf("rows left: got %v; want %v", left, want)
+			}
+		})
+	})
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-sql-driver/mysql"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,8 +17,17 @@ type User struct {
 	Created        time.Time
 }
 
+// UserStore describes the user operations the web app depends on.
+type UserStore interface {
+	Insert(name, email, password string) error
+	Auth(email, password string) (int, error)
+	Exists(id int) (bool, error)
+	Role(id int) (string, error)
+}
+
 type UserModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Dialect Dialect
 }
 
 func (m *UserModel) Insert(name, email, password string) error {
@@ -28,21 +36,16 @@ func (m *UserModel) Insert(name, email, password string) error {
 	if err != nil {
 		return err
 	}
-	stmt := `INSERT INTO users (name, email, hashed_pwd, created) VALUES (?, ?, ?, UTC_TIMESTAMP())`
+	stmt := `INSERT INTO users (name, email, hashed_pwd, created) VALUES (?, ?, ?, ?)`
 	fmt.Printf("Creds (inc pwd hash): %s, %s, %s\n", name, email, pwdHash)
-	_, err = m.DB.Exec(stmt, name, email, string(pwdHash))
+	_, err = m.DB.Exec(m.Dialect.rebind(stmt), name, email, string(pwdHash), now())
 	fmt.Println("Insert user model 2")
 	if err != nil {
 		fmt.Println("Insert user model 3")
-		var mySqlError *mysql.MySQLError
-		// Using errors.As to check wether the error has the time *mysql.MySQLError. If so, assigning the error
-		if errors.As(err, &mySqlError) {
-			// If the error relates to our constraint, returning specific error
-			if mySqlError.Number == 1062 {
-				// if mySqlError.Number == 1062 && strings.Contains(mySqlError.Message, "users_uc_email") {
-				fmt.Println("Yes, ErrDuplicateEmail")
-				return ErrDuplicateEmail
-			}
+		// If the error relates to our users_uc_email constraint, returning specific error
+		if m.Dialect.isDuplicate(err) {
+			fmt.Println("Yes, ErrDuplicateEmail")
+			return ErrDuplicateEmail
 		}
 		return err
 	}
@@ -53,7 +56,7 @@ func (m *UserModel) Auth(email, password string) (int, error) {
 	var pwdHash []byte
 	stmt := `SELECT id, hashed_pwd FROM users WHERE email = ?`
 	// check for creds
-	err := m.DB.QueryRow(stmt, email).Scan(&id, &pwdHash)
+	err := m.DB.QueryRow(m.Dialect.rebind(stmt), email).Scan(&id, &pwdHash)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, ErrInvalidCreds
@@ -76,6 +79,20 @@ func (m *UserModel) Auth(email, password string) (int, error) {
 func (m *UserModel) Exists(id int) (bool, error) {
 	var exists bool
 	stmt := `SELECT EXISTS(SELECT true FROM users WHERE id = ?)`
-	err := m.DB.QueryRow(stmt, id).Scan(&exists)
+	err := m.DB.QueryRow(m.Dialect.rebind(stmt), id).Scan(&exists)
 	return exists, err
 }
+
+// Role returns the user's role, "user" unless an admin changed it.
+func (m *UserModel) Role(id int) (string, error) {
+	var role string
+	stmt := `SELECT role FROM users WHERE id = ?`
+	err := m.DB.QueryRow(m.Dialect.rebind(stmt), id).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoRecord
+		}
+		return "", err
+	}
+	return role, nil
+}
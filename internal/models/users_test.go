@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestUserModelInsert(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &UserModel{DB: db, Dialect: dialect}
+		if err := m.Insert("Alice", "alice@example.com", "pa55word"); err != nil {
+			t.Fatal(err)
+		}
+		// The users_uc_email constraint, recognised by Dialect.isDuplicate
+		err := m.Insert("Alice again", "alice@example.com", "pa55word")
+		if !errors.Is(err, ErrDuplicateEmail) {
+			t.Errorf("got %v; want %v", err, ErrDuplicateEmail)
+		}
+	})
+}
+
+func TestUserModelAuth(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &UserModel{DB: db, Dialect: dialect}
+		if err := m.Insert("Alice", "alice@example.com", "pa55word"); err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			name     string
+			email    string
+			password string
+			wantID   int
+			wantErr  error
+		}{
+			{"Valid", "alice@example.com", "pa55word", 1, nil},
+			{"Wrong password", "alice@example.com", "password", 0, ErrInvalidCreds},
+			{"Unknown email", "bob@example.com", "pa55word", 0, ErrInvalidCreds},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				id, err := m.Auth(tt.email, tt.password)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v; want %v", err, tt.wantErr)
+				}
+				if id != tt.wantID {
+					t.Errorf("got id %d; want %d", id, tt.wantID)
+				}
+			})
+		}
+	})
+}
+
+func TestUserModelExists(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &UserModel{DB: db, Dialect: dialect}
+		if err := m.Insert("Alice", "alice@example.com", "pa55word"); err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			name   string
+			userID int
+			want   bool
+		}{
+			{"Valid ID", 1, true},
+			{"Zero ID", 0, false},
+			{"Non-existent ID", 2, false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				exists, err := m.Exists(tt.userID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if exists != tt.want {
+					t.Errorf("got %t; want %t", exists, tt.want)
+				}
+			})
+		}
+	})
+}
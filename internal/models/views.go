@@ -0,0 +1,90 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// MaxViewLimit caps NewSnippet.MaxViews.
+const MaxViewLimit = 1000
+
+// nullViews stores an unlimited view count (0) as NULL.
+func nullViews(n int) any {
+	if n <= 0 {
+		return nil
+	}
+	return n
+}
+
+// Consume uses up one view of a snippet with a view limit and returns how many
+// are left. The decrement is a single conditional UPDATE, so when two readers
+// race for the last view only one of them gets it; the other sees ErrNoRecord.
+// The view that brings the count to zero deletes the snippet for good.
+func (m *SnippetModel) Consume(id int) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET views_left = views_left - 1 WHERE id = ? AND views_left > 0 AND expires > ? AND deleted IS NULL`
+	result, err := tx.Exec(m.Dialect.rebind(stmt), id, now())
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return 0, ErrNoRecord
+	}
+	var left int
+	query := `SELECT views_left FROM snippets WHERE id = ?`
+	if err = tx.QueryRow(m.Dialect.rebind(query), id).Scan(&left); err != nil {
+		return 0, err
+	}
+	if left == 0 {
+		// Burnt: revisions and tags go with it (ON DELETE CASCADE)
+		stmt = `DELETE FROM snippets WHERE id = ?`
+		if _, err = tx.Exec(m.Dialect.rebind(stmt), id); err != nil {
+			return 0, err
+		}
+	}
+	return left, tx.Commit()
+}
+
+// addViewsBatch caps the snippets one AddViews statement updates,
+// keeping it well under every driver's placeholder limit.
+const addViewsBatch = 500
+
+// AddViews adds counts[id] reads to the view count of each snippet, one
+// UPDATE per batch of snippets rather than one per read. Snippets that
+// have gone since are skipped.
+func (m *SnippetModel) AddViews(counts map[int]int) error {
+	ids := make([]int, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	// A fixed order keeps concurrent flushes from locking rows in opposite orders
+	slices.Sort(ids)
+	for len(ids) > 0 {
+		batch := ids[:min(len(ids), addViewsBatch)]
+		ids = ids[len(batch):]
+		var cases strings.Builder
+		args := make([]any, 0, 3*len(batch))
+		for _, id := range batch {
+			cases.WriteString(" WHEN ? THEN ?")
+			args = append(args, id, counts[id])
+		}
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		stmt := `UPDATE snippets SET view_count = view_count + CASE id` + cases.String() + ` ELSE 0 END
+		WHERE id IN (?` + strings.Repeat(", ?", len(batch)-1) + `)`
+		if _, err := m.DB.Exec(m.Dialect.rebind(stmt), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestSnippetModelConsume(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect}
+		id, err := m.Insert(NewSnippet{Title: "x", Files: []File{{Name: "x", Content: "x"}}, Expires: "1d", MaxViews: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		unlimited, err := m.Insert(NewSnippet{Title: "x", Files: []File{{Name: "x", Content: "x"}}, Expires: "1d"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := func(table, column string, id int) int {
+			var n int
+			query := `SELECT COUNT(*) FROM ` + table + ` WHERE ` + column + ` = ?`
+			if err := db.QueryRow(dialect.rebind(query), id).Scan(&n); err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+
+		tests := []struct {
+			name     string
+			id       int
+			wantLeft int
+			wantErr  error
+			wantRows int // Rows left in snippets for the id afterwards
+		}{
+			{"First view", id, 2, nil, 1},
+			{"Second view", id, 1, nil, 1},
+			// The last view burns the snippet
+			{"Last view", id, 0, nil, 0},
+			{"After the last view", id, 0, ErrNoRecord, 0},
+			{"Unlimited views", unlimited, 0, ErrNoRecord, 1},
+			{"Missing snippet", unlimited + 1, 0, ErrNoRecord, 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				left, err := m.Consume(tt.id)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v; want %v", err, tt.wantErr)
+				}
+				if left != tt.wantLeft {
+					t.Errorf("got %d views left; want %d", left, tt.wantLeft)
+				}
+				if got := count("snippets", "id", tt.id); got != tt.wantRows {
+					t.Errorf("got %d snippet rows; want %d", got, tt.wantRows)
+				}
+			})
+		}
+		if s, err := m.Get(unlimited); err != nil || s.ViewsLeft != 0 {
+			t.Errorf("unlimited snippet: got %+v, %v; want it untouched", s, err)
+		}
+		// Revisions and files go with the burnt snippet
+		if n := count("snippet_revisions", "snippet_id", id) + count("snippet_files", "snippet_id", id); n != 0 {
+			t.Errorf("got %d revision and file rows left; want 0", n)
+		}
+	})
+}
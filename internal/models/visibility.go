@@ -0,0 +1,43 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+)
+
+// Visibility decides who can see a snippet and where it is listed.
+type Visibility string
+
+const (
+	Public   Visibility = "public"   // Listed, searchable, visible to everyone
+	Unlisted Visibility = "unlisted" // Only reachable through the share token URL
+	Private  Visibility = "private"  // Only visible to the owner
+)
+
+// Visibilities in the order the create form offers them
+var Visibilities = []Visibility{Public, Unlisted, Private}
+
+// newShareToken returns 32 random bytes, base64url encoded (43 chars).
+func newShareToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GetShared returns the live unlisted snippet behind a share token.
+func (m *SnippetModel) GetShared(token string) (*Snippet, error) {
+	query := `SELECT id FROM snippets WHERE share_token = ? AND visibility = 'unlisted' AND expires > ? AND deleted IS NULL`
+	var id int
+	err := m.DB.QueryRow(m.Dialect.rebind(query), token, now()).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+	return m.Get(id)
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSnippetModelVisibility(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *sql.DB, dialect Dialect) {
+		m := &SnippetModel{DB: db, Dialect: dialect}
+		users := &UserModel{DB: db, Dialect: dialect}
+		if err := users.Insert("Alice", "alice@example.com", "pa55word"); err != nil {
+			t.Fatal(err)
+		}
+		ids := map[Visibility]int{}
+		for _, v := range Visibilities {
+			id, err := m.Insert(NewSnippet{
+				Title:      "An old silent pond",
+				Files:      []File{{Name: "haiku.txt", Content: "A frog jumps into the pond"}},
+				Expires:    "1d",
+				OwnerID:    1,
+				Visibility: v,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids[v] = id
+		}
+		public := []int{ids[Public]}
+
+		t.Run("Listings show public snippets only", func(t *testing.T) {
+			latest, err := m.Latest10()
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, err := m.Page(ListOptions{Sort: SortNewest, Size: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, err := m.Search("pond", 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, snippets := range map[string][]*Snippet{
+				"Latest10": latest, "Page": page.Snippets, "Search": results.Snippets,
+			} {
+				var got []int
+				for _, s := range snippets {
+					got = append(got, s.ID)
+				}
+				if !slices.Equal(got, public) {
+					t.Errorf("%s: got %v; want %v", name, got, public)
+				}
+			}
+		})
+
+		t.Run("Only unlisted snippets get a share token", func(t *testing.T) {
+			for _, v := range Visibilities {
+				s, err := m.Get(ids[v])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s.Visibility != v {
+					t.Errorf("got visibility %q; want %q", s.Visibility, v)
+				}
+				if got := len(s.ShareToken); (v == Unlisted) != (got == 43) {
+					t.Errorf("%s: got a share token of %d chars", v, got)
+				}
+			}
+		})
+
+		unlisted, err := m.Get(ids[Unlisted])
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			name    string
+			token   string
+			wantID  int
+			wantErr error
+		}{
+			{"Right token", unlisted.ShareToken, ids[Unlisted], nil},
+			{"Wrong token", "wrong-token", 0, ErrNoRecord},
+			{"Token in the wrong case", swapCase(unlisted.ShareToken), 0, ErrNoRecord},
+			{"Empty token", "", 0, ErrNoRecord},
+		}
+		for _, tt := range tests {
+			t.Run("GetShared/"+tt.name, func(t *testing.T) {
+				s, err := m.GetShared(tt.token)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v; want %v", err, tt.wantErr)
+				}
+				if err == nil && s.ID != tt.wantID {
+					t.Errorf("got snippet %d; want %d", s.ID, tt.wantID)
+				}
+			})
+		}
+	})
+}
+
+// swapCase flips the case of every ASCII letter, for a token that differs
+// from the original only in case.
+func swapCase(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case 'a' <= c && c <= 'z':
+			b[i] = c - 'a' + 'A'
+		case 'A' <= c && c <= 'Z':
+			b[i] = c - 'A' + 'a'
+		}
+	}
+	return string(b)
+}
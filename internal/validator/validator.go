@@ -8,6 +8,12 @@ import (
 
 var EmailRegex = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// Tags are lower case words that may carry a few symbols, e.g. c++, c#, node.js
+var TagRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]*$`)
+
+// File names are a single path segment such as main.go, go.mod or .env
+var FileNameRegex = regexp.MustCompile(`^\.?[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
 func MinChars(val string, n int) bool {
 	// True if a val contains at least n chars
 	return utf8.RuneCountInString(val) >= n
@@ -57,6 +63,16 @@ func MaxChars(s string, n int) bool {
 	return utf8.RuneCountInString(s) <= n
 }
 
+// Returns true if val is one of the permitted values
+func PermittedValue[T comparable](val T, permittedVals ...T) bool {
+	for i := range permittedVals {
+		if val == permittedVals[i] {
+			return true
+		}
+	}
+	return false
+}
+
 // Returns true if in a list of permitted ints
 func PermittedInt(val int, permittedVals ...int) bool {
 	for i := range permittedVals {
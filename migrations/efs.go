@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// Numbered up/down migrations, one directory per SQL dialect.
+// File names follow NNNN_name.up.sql / NNNN_name.down.sql.
+//
+//go:embed "mysql" "sqlite" "postgres"
+var Files embed.FS
@@ -0,0 +1,7 @@
+-- Session table used by github.com/alexedwards/scs/mysqlstore
+CREATE TABLE IF NOT EXISTS sessions (
+    token CHAR(43) PRIMARY KEY,
+    data BLOB NOT NULL,
+    expiry TIMESTAMP(6) NOT NULL,
+    INDEX sessions_expiry_idx (expiry)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE snippet_revisions (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    author_id INTEGER NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT snippet_revisions_uc_revision UNIQUE (snippet_id, revision),
+    CONSTRAINT fk_snippet_revisions_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT fk_snippet_revisions_author FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Existing snippets get their current text as revision 1, with no known author
+INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+SELECT id, 1, title, content, created FROM snippets;
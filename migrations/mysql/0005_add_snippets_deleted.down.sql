@@ -0,0 +1,3 @@
+DROP INDEX idx_snippets_deleted ON snippets;
+
+ALTER TABLE snippets DROP COLUMN deleted;
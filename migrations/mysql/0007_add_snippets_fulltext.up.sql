@@ -0,0 +1,2 @@
+-- Natural language full-text search over title and content
+CREATE FULLTEXT INDEX ft_snippets_title_content ON snippets(title, content);
@@ -0,0 +1,5 @@
+ALTER TABLE snippets DROP FOREIGN KEY fk_snippets_owner;
+
+DROP INDEX idx_snippets_owner ON snippets;
+
+ALTER TABLE snippets DROP COLUMN owner_id;
@@ -0,0 +1,8 @@
+-- The user who created the snippet; NULL for snippets from before logins were recorded
+ALTER TABLE snippets ADD COLUMN owner_id INTEGER NULL,
+    ADD CONSTRAINT fk_snippets_owner FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_snippets_owner ON snippets(owner_id);
+
+-- Until now the author of revision 1 stood in for the owner
+UPDATE snippets SET owner_id = (SELECT r.author_id FROM snippet_revisions r WHERE r.snippet_id = snippets.id AND r.revision = 1);
@@ -0,0 +1,5 @@
+DROP INDEX idx_snippets_share_token ON snippets;
+
+ALTER TABLE snippets DROP COLUMN share_token;
+
+ALTER TABLE snippets DROP COLUMN visibility;
@@ -0,0 +1,6 @@
+-- public: listed everywhere, unlisted: only reachable through share_token, private: owner only
+ALTER TABLE snippets ADD COLUMN visibility VARCHAR(10) NOT NULL DEFAULT 'public';
+
+ALTER TABLE snippets ADD COLUMN share_token VARCHAR(64) NULL;
+
+CREATE UNIQUE INDEX idx_snippets_share_token ON snippets(share_token);
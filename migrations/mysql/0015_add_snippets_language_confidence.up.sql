@@ -0,0 +1,3 @@
+-- How sure the language detector was of language, 0 to 1.
+-- NULL when a person picked the language.
+ALTER TABLE snippets ADD COLUMN language_confidence DOUBLE NULL;
@@ -0,0 +1,19 @@
+-- The named files of a snippet, in display order from position 1.
+-- File 1 is the main file: snippets.content and snippets.language mirror it,
+-- so revisions, editing and search keep working on the snippets table.
+CREATE TABLE snippet_files (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    language VARCHAR(30) NOT NULL DEFAULT '',
+    language_confidence DOUBLE NULL,
+    content TEXT NOT NULL,
+    CONSTRAINT snippet_files_uc_position UNIQUE (snippet_id, position),
+    CONSTRAINT snippet_files_uc_name UNIQUE (snippet_id, name),
+    CONSTRAINT fk_snippet_files_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+-- Existing snippets become single-file snippets
+INSERT INTO snippet_files (snippet_id, position, name, language, language_confidence, content)
+SELECT id, 1, 'snippet.txt', language, language_confidence, content FROM snippets;
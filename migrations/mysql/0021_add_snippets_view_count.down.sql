@@ -0,0 +1,3 @@
+DROP INDEX idx_snippets_view_count ON snippets;
+
+ALTER TABLE snippets DROP COLUMN view_count;
@@ -0,0 +1,3 @@
+DROP TABLE collection_snippets;
+
+DROP TABLE collections;
@@ -0,0 +1,3 @@
+ALTER TABLE collections MODIFY share_token VARCHAR(64) NULL;
+
+ALTER TABLE snippets MODIFY share_token VARCHAR(64) NULL;
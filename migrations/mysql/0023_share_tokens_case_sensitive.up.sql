@@ -0,0 +1,5 @@
+-- Share tokens are base64url, so case matters: compare them byte for byte
+-- instead of through the case-insensitive default collation.
+ALTER TABLE snippets MODIFY share_token VARCHAR(64) CHARACTER SET ascii COLLATE ascii_bin NULL;
+
+ALTER TABLE collections MODIFY share_token VARCHAR(64) CHARACTER SET ascii COLLATE ascii_bin NULL;
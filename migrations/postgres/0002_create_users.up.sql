@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    hashed_pwd CHAR(60) NOT NULL,
+    created TIMESTAMP NOT NULL,
+    CONSTRAINT users_uc_email UNIQUE (email)
+);
@@ -0,0 +1,2 @@
+-- The expression must match the one in SnippetModel.Search for the index to be used
+CREATE INDEX idx_snippets_fts ON snippets USING GIN (to_tsvector('english', title || ' ' || content));
@@ -0,0 +1,2 @@
+-- Views left before the snippet destroys itself, NULL when views are unlimited
+ALTER TABLE snippets ADD COLUMN views_left INTEGER NULL;
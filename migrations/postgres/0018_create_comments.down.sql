@@ -0,0 +1,3 @@
+ALTER TABLE snippets DROP COLUMN comments_locked;
+
+DROP TABLE comments;
@@ -0,0 +1,20 @@
+-- Discussion under a snippet. Replies point at their parent comment.
+-- Deleting a comment only blanks it (deleted is set), so its replies stay in place.
+CREATE TABLE comments (
+    id SERIAL PRIMARY KEY,
+    snippet_id INTEGER NOT NULL,
+    parent_id INTEGER NULL,
+    author_id INTEGER NULL,
+    body TEXT NOT NULL,
+    created TIMESTAMP NOT NULL,
+    edited TIMESTAMP NULL,
+    deleted TIMESTAMP NULL,
+    CONSTRAINT fk_comments_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_parent FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_author FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_comments_snippet ON comments(snippet_id);
+
+-- Set while the owner has locked the thread
+ALTER TABLE snippets ADD COLUMN comments_locked TIMESTAMP NULL;
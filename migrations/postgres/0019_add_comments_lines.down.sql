@@ -0,0 +1,3 @@
+ALTER TABLE comments DROP COLUMN line_end;
+ALTER TABLE comments DROP COLUMN line_start;
+ALTER TABLE comments DROP COLUMN revision;
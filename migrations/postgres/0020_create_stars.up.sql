@@ -0,0 +1,12 @@
+-- Snippets users have starred. The primary key lets each user star a
+-- snippet only once.
+CREATE TABLE stars (
+    user_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    created TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, snippet_id),
+    CONSTRAINT fk_stars_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_stars_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_stars_snippet ON stars(snippet_id);
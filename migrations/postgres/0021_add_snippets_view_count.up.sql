@@ -0,0 +1,5 @@
+-- Reads of the snippet page. Views are counted in memory and added
+-- here in batches, so the count can trail by one flush interval.
+ALTER TABLE snippets ADD COLUMN view_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_snippets_view_count ON snippets(view_count);
@@ -0,0 +1 @@
+-- Nothing to do: Postgres already compares share tokens case-sensitively.
@@ -0,0 +1,4 @@
+-- Soft delete: set when the owner moves a snippet to the trash, NULL otherwise
+ALTER TABLE snippets ADD COLUMN deleted DATETIME NULL;
+
+CREATE INDEX idx_snippets_deleted ON snippets(deleted);
@@ -0,0 +1,2 @@
+-- Backs the "expiring soon" listing order and the reaper's expiry scans
+CREATE INDEX idx_snippets_expires ON snippets(expires);
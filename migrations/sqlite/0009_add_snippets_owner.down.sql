@@ -0,0 +1,3 @@
+DROP INDEX idx_snippets_owner;
+
+ALTER TABLE snippets DROP COLUMN owner_id;
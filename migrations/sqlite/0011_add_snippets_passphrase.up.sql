@@ -0,0 +1,2 @@
+-- bcrypt hash of the snippet's own passphrase, NULL for snippets without one
+ALTER TABLE snippets ADD COLUMN passphrase_hash CHAR(60) NULL;
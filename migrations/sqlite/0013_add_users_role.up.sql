@@ -0,0 +1,2 @@
+-- Roles pick the per-role expiry limits, e.g. -expiry-max="user=7d,admin=never"
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
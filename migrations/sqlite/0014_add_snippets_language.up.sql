@@ -0,0 +1,2 @@
+-- Highlighting language (a chroma lexer name), '' for plain text
+ALTER TABLE snippets ADD COLUMN language VARCHAR(30) NOT NULL DEFAULT '';
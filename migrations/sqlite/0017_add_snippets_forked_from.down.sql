@@ -0,0 +1,3 @@
+DROP INDEX idx_snippets_forked_from;
+
+ALTER TABLE snippets DROP COLUMN forked_from;
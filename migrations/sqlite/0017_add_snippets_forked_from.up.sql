@@ -0,0 +1,4 @@
+-- The snippet this one was forked from; NULL for originals and once the parent is gone
+ALTER TABLE snippets ADD COLUMN forked_from INTEGER NULL REFERENCES snippets(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_snippets_forked_from ON snippets(forked_from);
@@ -0,0 +1,5 @@
+-- Review comments are attached to lines line_start to line_end of the
+-- snippet as it was at revision. All three are NULL for general comments.
+ALTER TABLE comments ADD COLUMN revision INTEGER NULL;
+ALTER TABLE comments ADD COLUMN line_start INTEGER NULL;
+ALTER TABLE comments ADD COLUMN line_end INTEGER NULL;
@@ -0,0 +1,3 @@
+DROP INDEX idx_snippets_view_count;
+
+ALTER TABLE snippets DROP COLUMN view_count;
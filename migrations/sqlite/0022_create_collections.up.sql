@@ -0,0 +1,26 @@
+-- Named, ordered lists of snippets kept by a user. Visibility works as for
+-- snippets: unlisted collections are only reachable through share_token.
+CREATE TABLE collections (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    visibility VARCHAR(10) NOT NULL DEFAULT 'public',
+    share_token VARCHAR(64) NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT fk_collections_owner FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_collections_owner ON collections(owner_id);
+CREATE UNIQUE INDEX idx_collections_share_token ON collections(share_token);
+
+-- A snippet is at most once in a collection. Entries are shown by position.
+CREATE TABLE collection_snippets (
+    collection_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (collection_id, snippet_id),
+    CONSTRAINT fk_collection_snippets_collection FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    CONSTRAINT fk_collection_snippets_snippet FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_collection_snippets_snippet ON collection_snippets(snippet_id);
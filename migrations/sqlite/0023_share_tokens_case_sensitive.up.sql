@@ -0,0 +1 @@
+-- Nothing to do: SQLite already compares share tokens case-sensitively.